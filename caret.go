@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wideRanges lists the Unicode ranges whose characters occupy two
+// columns on a terminal: the East Asian Wide and Fullwidth
+// characters, including the full-width digits and letters that are
+// easily pasted into a duration by mistake.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115f},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe30, 0xfe4f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x1f300, 0x1f64f},
+	{0x1f900, 0x1f9ff},
+	{0x20000, 0x2fffd},
+	{0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of terminal columns r occupies: 0 for
+// combining marks and other zero-width characters, 2 for wide
+// characters and 1 otherwise.
+func runeWidth(r rune) int {
+	if r == utf8.RuneError {
+		return 1
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, wide := range wideRanges {
+		if r >= wide.lo && r <= wide.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// displayWidth returns the number of terminal columns s occupies.
+// Bytes that are not valid UTF-8 count as one column each.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// errorSpan returns the byte offset just past the token that starts
+// at position in input, so that a diagnostic can underline all of it.
+// A token starting with a digit is a number together with the unit
+// that follows it, such as "30d" or "1_000ms"; any other token is the
+// run of characters up to the next digit, such as an unknown unit
+// "sec".
+// Tokens never extend over whitespace or quotes, so the span stays
+// within a single word of an HAProxy configuration line. At least
+// one character is always included unless position is at the end of
+// input.
+func errorSpan(input string, position int) int {
+	if position >= len(input) {
+		return position
+	}
+
+	isBoundary := func(c byte) bool {
+		return c == ' ' || c == '\t' || c == '"' || c == '\''
+	}
+
+	end := position
+	if isDigit(input[end]) {
+		if _, numEnd, bad := scanDecimal(input, end); bad < 0 {
+			end = numEnd
+		}
+		for end < len(input) && isDigit(input[end]) {
+			end++
+		}
+	}
+	for end < len(input) && !isDigit(input[end]) && !isBoundary(input[end]) {
+		end++
+	}
+
+	if end == position {
+		_, size := utf8.DecodeRuneInString(input[position:])
+		end += size
+	}
+
+	return end
+}
+
+// caretLine returns the line printed beneath input to mark the errors
+// at the given byte offsets, which must be in increasing order: a '^'
+// under the first character of each offending token, followed by a
+// '~' under each remaining column of it. A token is cut short where
+// the next error begins. The padding reproduces any tabs in input and
+// accounts for the display width of multi-byte and wide characters,
+// so the markers line up however the terminal, or file, renders them.
+func caretLine(input string, positions ...int) string {
+	var b strings.Builder
+	done := 0
+
+	for i, position := range positions {
+		if position > len(input) {
+			position = len(input)
+		}
+		if i > 0 && position < done {
+			continue
+		}
+
+		for _, r := range input[done:position] {
+			if r == '\t' {
+				b.WriteByte('\t')
+			} else {
+				b.WriteString(strings.Repeat(" ", runeWidth(r)))
+			}
+		}
+
+		end := errorSpan(input, position)
+		if i+1 < len(positions) && positions[i+1] > position && positions[i+1] < end {
+			end = positions[i+1]
+		}
+
+		width := displayWidth(input[position:end])
+		b.WriteByte('^')
+		if width > 1 {
+			b.WriteString(strings.Repeat("~", width-1))
+		}
+
+		done = end
+	}
+
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package main_test
+
+import (
+	"testing"
+
+	cmd "github.com/frobware/haproxytime"
+)
+
+func TestCaretLine(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		positions   []int
+		expected    string
+	}{{
+		description: "number and unit are underlined",
+		input:       "24d20h31m23s647ms1000us",
+		positions:   []int{17},
+		expected:    "                 ^~~~~~",
+	}, {
+		description: "unknown unit is underlined up to the next digit",
+		input:       "1h30sec5m",
+		positions:   []int{4},
+		expected:    "    ^~~",
+	}, {
+		description: "fraction is underlined with its number",
+		input:       "1h2.5x",
+		positions:   []int{2},
+		expected:    "  ^~~~",
+	}, {
+		description: "end of input",
+		input:       "2h",
+		positions:   []int{2},
+		expected:    "  ^",
+	}, {
+		description: "tabs are reproduced",
+		input:       "\ttimeout server\t2h3x",
+		positions:   []int{19},
+		expected:    "\t              \t   ^",
+	}, {
+		description: "multi-byte characters occupy one column",
+		input:       "5µs3x",
+		positions:   []int{5},
+		expected:    "    ^",
+	}, {
+		description: "full-width digits occupy two columns",
+		input:       "１０s",
+		positions:   []int{0},
+		expected:    "^~~~~",
+	}, {
+		description: "after full-width digits",
+		input:       "１０s3x",
+		positions:   []int{8},
+		expected:    "      ^",
+	}, {
+		description: "non-breaking space",
+		input:       "1h 30m",
+		positions:   []int{2},
+		expected:    "  ^",
+	}, {
+		description: "token ends at a quote",
+		input:       `timeout client "1h2x"`,
+		positions:   []int{19},
+		expected:    "                   ^",
+	}, {
+		description: "several errors",
+		input:       "1x2h3y",
+		positions:   []int{1, 5},
+		expected:    " ^   ^",
+	}, {
+		description: "a span is cut short by the next error",
+		input:       "30d5x",
+		positions:   []int{0, 2},
+		expected:    "^~^",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			if got := cmd.CaretLine(tc.input, tc.positions...); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/frobware/comptime"
+)
+
+// invalidClockField is the cause of a syntax error for a field of a
+// clock time, other than the first, that is not two digits from 00 to
+// 59, or for a clock time with more than three fields.
+const invalidClockField = variableLengthUnit + 1
+
+// isClock reports whether input is written as a clock time, such as
+// "01:30:00", which always contains a colon.
+func isClock(input string) bool {
+	return strings.Contains(input, ":")
+}
+
+// scanClock parses input as a clock time, "[Nd ][H:]M:SS[.fff]", such
+// as "01:30:00", "2:05.250" or "1d 04:00:00", and returns the total
+// along with every problem found, in order of position, as
+// scanDuration does. The first field may have any number of digits
+// and every other field must be two digits from 00 to 59. The seconds
+// may have a fraction, provided the total is a whole number of
+// microseconds.
+func scanClock(input string, d *directive) (time.Duration, []error) {
+	scanDigits := func(position int) int {
+		for position < len(input) && isDigit(input[position]) {
+			position++
+		}
+		return position
+	}
+
+	var errs []error
+	total := durationTotal{max: d.max}
+
+	add := func(number decimal, unit time.Duration, position int) {
+		composite, err := number.duration(unit, position)
+		if err == nil {
+			err = total.add(composite, position)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// An optional number of days, as in "1d 04:00:00".
+	position := 0
+	if end := scanDigits(0); end > 0 && end < len(input) && input[end] == 'd' {
+		add(decimal{digits: input[:end]}, 24*time.Hour, 0)
+		position = end + 1
+		for position < len(input) && isBlank(input[position]) {
+			position++
+		}
+	}
+
+	// A fourth field is reported at its colon, after any problem
+	// in the first three.
+	fields := strings.Split(input[position:], ":")
+	extra := -1
+	if len(fields) > 3 {
+		extra = position + len(fields[0]) + len(fields[1]) + len(fields[2]) + 2
+		fields = fields[:3]
+	}
+	units := []time.Duration{time.Hour, time.Minute, time.Second}[3-len(fields):]
+
+	for i, field := range fields {
+		fieldStart := position
+		end := scanDigits(position)
+		digits := input[position:end]
+		position += len(field) + 1
+
+		fraction := ""
+		last := i == len(fields)-1
+		if last && end < len(input) && input[end] == '.' {
+			fracEnd := scanDigits(end + 1)
+			if fracEnd == end+1 {
+				errs = append(errs, syntaxError(comptime.InvalidNumber, end+1))
+				continue
+			}
+			fraction = input[end+1 : fracEnd]
+			end = fracEnd
+		}
+
+		switch {
+		case digits == "":
+			errs = append(errs, syntaxError(comptime.InvalidNumber, fieldStart))
+			continue
+		case i > 0 && (len(digits) != 2 || digits[0] > '5'):
+			errs = append(errs, syntaxError(invalidClockField, fieldStart))
+			continue
+		case end < fieldStart+len(field):
+			errs = append(errs, syntaxError(comptime.InvalidUnit, end))
+			continue
+		}
+
+		add(decimal{digits: digits + fraction, exponent: -len(fraction)}, units[i], fieldStart)
+	}
+	if extra >= 0 {
+		errs = append(errs, syntaxError(invalidClockField, extra))
+	}
+
+	return total.total, errs
+}
+
+// formatClockTime returns duration as a clock time, "[Nd ]HH:MM:SS[.mmm]",
+// for example "01:30:00" for 5400000ms or "1d 00:00:00.500" for
+// 86400500ms. Any sub-millisecond component is ignored.
+func formatClockTime(duration time.Duration) string {
+	parts := splitDuration(duration)
+
+	result := fmt.Sprintf("%02d:%02d:%02d", parts.hours, parts.minutes, parts.seconds)
+	if parts.milliseconds > 0 {
+		result += fmt.Sprintf(".%03d", parts.milliseconds)
+	}
+	if parts.days > 0 {
+		result = fmt.Sprintf("%dd %s", parts.days, result)
+	}
+
+	return result
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strings"
+)
+
+// configToken is a single word from an HAProxy configuration line
+// after quote and escape processing.
+type configToken struct {
+	// text is the token with quotes removed and escapes
+	// resolved; it is what HAProxy would see as the argument.
+	text string
+
+	// start and end are the byte offsets of the raw token within
+	// the line, end being exclusive. Any surrounding quotes are
+	// included in the span.
+	start, end int
+
+	// offsets maps each byte of text to the byte offset in the
+	// line that produced it.
+	offsets []int
+}
+
+// configLine is one line of an HAProxy configuration file split into
+// tokens.
+type configLine struct {
+	// number is the 1-based line number within the file.
+	number int
+
+	// text is the raw line with any trailing "\r" removed.
+	text string
+
+	// tokens are the words on the line, excluding comments.
+	tokens []configToken
+}
+
+// column returns the 0-based byte offset in the line of the byte at
+// position pos within the token's text. Positions at or past the end
+// of text map to the end of the raw token, which keeps carets for
+// errors reported at end of input within the token's span.
+func (t configToken) column(pos int) int {
+	if pos < 0 {
+		return t.start
+	}
+	if pos >= len(t.offsets) {
+		return t.end
+	}
+	return t.offsets[pos]
+}
+
+// parseConfig splits the contents of an HAProxy configuration file
+// into lines and tokens following the same quoting rules as HAProxy:
+// words are separated by spaces or tabs, a backslash escapes the next
+// character, single quotes preserve their contents literally, double
+// quotes permit backslash escapes, and an unquoted '#' starts a
+// comment that runs to the end of the line.
+func parseConfig(data string) []configLine {
+	var lines []configLine
+
+	for i, text := range strings.Split(data, "\n") {
+		text = strings.TrimSuffix(text, "\r")
+		lines = append(lines, configLine{
+			number: i + 1,
+			text:   text,
+			tokens: tokenizeConfigLine(text),
+		})
+	}
+
+	// A file ending in a newline does not have an extra empty
+	// line after it.
+	if n := len(lines); n > 1 && lines[n-1].text == "" {
+		lines = lines[:n-1]
+	}
+
+	return lines
+}
+
+// tokenizeConfigLine splits a single configuration line into tokens.
+// See parseConfig for the quoting rules.
+func tokenizeConfigLine(line string) []configToken {
+	var tokens []configToken
+	var tok *configToken
+	var quote byte
+
+	begin := func(pos int) {
+		if tok == nil {
+			tok = &configToken{start: pos}
+		}
+	}
+
+	emit := func(pos int) {
+		if tok != nil {
+			tok.end = pos
+			tokens = append(tokens, *tok)
+			tok = nil
+		}
+	}
+
+	// add appends the byte at pos to the current token. Slicing
+	// the line, rather than converting the byte to a string,
+	// keeps multi-byte characters intact.
+	add := func(pos int) {
+		tok.text += line[pos : pos+1]
+		tok.offsets = append(tok.offsets, pos)
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				add(i)
+			}
+		case c == '\\' && i+1 < len(line):
+			begin(i)
+			i++
+			add(i)
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				add(i)
+			}
+		case c == '"' || c == '\'':
+			begin(i)
+			quote = c
+		case c == ' ' || c == '\t':
+			emit(i)
+		case c == '#':
+			emit(i)
+			return tokens
+		default:
+			begin(i)
+			add(i)
+		}
+	}
+
+	emit(len(line))
+	return tokens
+}
+
+// words returns the text of each token on the line.
+func (l configLine) words() []string {
+	words := make([]string, len(l.tokens))
+	for i, tok := range l.tokens {
+		words[i] = tok.text
+	}
+	return words
+}
@@ -0,0 +1,52 @@
+package main_test
+
+import (
+	"reflect"
+	"testing"
+
+	cmd "github.com/frobware/haproxytime"
+)
+
+func TestConfigWords(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		expected    [][]string
+	}{{
+		description: "empty input",
+		input:       "",
+		expected:    [][]string{{}},
+	}, {
+		description: "simple directive",
+		input:       "timeout client 30s\n",
+		expected:    [][]string{{"timeout", "client", "30s"}},
+	}, {
+		description: "indentation and tabs",
+		input:       "defaults\n\ttimeout  server\t1m\n",
+		expected:    [][]string{{"defaults"}, {"timeout", "server", "1m"}},
+	}, {
+		description: "comments",
+		input:       "# comment\ntimeout client 5s # trailing\n",
+		expected:    [][]string{{}, {"timeout", "client", "5s"}},
+	}, {
+		description: "quoted and escaped words",
+		input:       `log-format "%ci # %b" 'a\b' c\ d` + "\n",
+		expected:    [][]string{{"log-format", "%ci # %b", `a\b`, "c d"}},
+	}, {
+		description: "multi-byte characters",
+		input:       "timeout client 1µs \"2µs\"\n",
+		expected:    [][]string{{"timeout", "client", "1µs", "2µs"}},
+	}, {
+		description: "CRLF line endings",
+		input:       "timeout client 5s\r\ntimeout server 6s\r\n",
+		expected:    [][]string{{"timeout", "client", "5s"}, {"timeout", "server", "6s"}},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			if got := cmd.ConfigWords(tc.input); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/frobware/comptime"
+)
+
+// unitDurations maps each comptime unit to the duration it
+// represents.
+var unitDurations = map[comptime.Unit]time.Duration{
+	comptime.Microsecond: time.Microsecond,
+	comptime.Millisecond: time.Millisecond,
+	comptime.Second:      time.Second,
+	comptime.Minute:      time.Minute,
+	comptime.Hour:        time.Hour,
+	comptime.Day:         24 * time.Hour,
+}
+
+// syntaxErrorMessages maps each comptime.SyntaxErrorCause to the
+// description comptime uses in its error messages.
+var syntaxErrorMessages = map[comptime.SyntaxErrorCause]string{
+	comptime.InvalidNumber:                        "invalid number",
+	comptime.InvalidUnit:                          "invalid unit",
+	comptime.InvalidUnitOrder:                     "invalid unit order",
+	comptime.UnexpectedCharactersInSingleUnitMode: "unexpected characters in single unit mode",
+	inexactFraction:                               "fraction is not a whole number of microseconds",
+	variableLengthUnit:                            "years and months have no fixed length",
+	invalidClockField:                             "clock field must be two digits from 00 to 59",
+	invalidOperator:                               "expected an operator",
+	unbalancedParenthesis:                         "unbalanced parenthesis",
+	unknownFunction:                               "unknown function",
+	durationProduct:                               "cannot multiply two durations",
+	durationDivisor:                               "cannot divide by a duration",
+	divisionByZero:                                "division by zero",
+}
+
+// diagnosticError is a problem found by diagnose after the first
+// one. It mirrors the comptime error of the same class, which cannot
+// be constructed outside that package, and prints the same message.
+type diagnosticError struct {
+	// class is "SyntaxError", "OverflowError" or "RangeError".
+	class string
+
+	// cause is the reason for a SyntaxError.
+	cause comptime.SyntaxErrorCause
+
+	// position is the 0-based byte offset in the input at which
+	// the problem was detected.
+	position int
+}
+
+// Error returns a message in the form comptime uses for the same
+// class of error.
+func (e *diagnosticError) Error() string {
+	switch e.class {
+	case "OverflowError":
+		return fmt.Sprintf("overflow error at position %d", e.position+1)
+	case "RangeError":
+		return fmt.Sprintf("range error at position %d", e.position+1)
+	}
+	return fmt.Sprintf("syntax error at position %d: %s", e.position+1, syntaxErrorMessages[e.cause])
+}
+
+// Position returns the 0-based position of the error in the input.
+func (e *diagnosticError) Position() int {
+	return e.position
+}
+
+// parseErrors holds every problem found in a single input, in the
+// order they appear. The first is the error returned by
+// comptime.ParseDuration, so code that only looks at one error, such
+// as exitStatus, sees the same error as before.
+type parseErrors []error
+
+// Error returns the messages of every error, separated by
+// semicolons.
+func (e parseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual errors.
+func (e parseErrors) Unwrap() []error {
+	return e
+}
+
+// Position returns the 0-based position of the first error.
+func (e parseErrors) Position() int {
+	return errorPosition(e[0])
+}
+
+// splitErrors returns the individual errors held by err if it
+// contains parseErrors, otherwise err on its own.
+func splitErrors(err error) []error {
+	var errs parseErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return []error{err}
+}
+
+// firstError returns the first of the errors held by err, as
+// splitErrors does.
+func firstError(err error) error {
+	return splitErrors(err)[0]
+}
+
+// errorPosition returns the 0-based position of err in its input, or
+// 0 if err has no position.
+func errorPosition(err error) int {
+	var posErr interface {
+		Position() int
+	}
+	if errors.As(err, &posErr) && posErr != nil {
+		return posErr.Position()
+	}
+	return 0
+}
+
+// inexactFraction is the cause of a syntax error for a value with a
+// fraction or negative exponent, such as "1.0000001s", that is not a
+// whole number of microseconds. It extends the causes defined by
+// comptime, which has neither.
+const inexactFraction = comptime.UnexpectedCharactersInSingleUnitMode + 1
+
+// diagnose scans input as scanDuration does, with comptime's plain
+// numbers, and returns every problem found. The first problem is
+// always the one comptime.ParseDuration reports.
+func diagnose(input string, d *directive, parseMode comptime.ParseMode) []error {
+	_, errs := scanDuration(input, d, parseMode, false)
+	return errs
+}
+
+// scanDuration parses input with the grammar of
+// comptime.ParseDuration and returns the total along with every
+// problem found, in order of position. If extended is true a number
+// may be written in any form scanDecimal accepts, such as "1.5h",
+// "2_147_483_647ms" or "1e3ms", and must come to a whole number of
+// microseconds. Units may then also be named as in unitNames, and
+// values separated as skipSeparators allows, as in "2 hours, 30
+// minutes", although input made only of separators is still an
+// invalid number.
+//
+// Rather than stopping at the first problem, it recovers and carries
+// on so that every syntax, ordering, overflow and range problem can be
+// reported at once. After an invalid number or unit it resumes at the
+// next digit. A value with a unit out of order is skipped, leaving
+// the order established by the values before it. Only the first value
+// that takes the total beyond d.max is reported, as every later value
+// would be too. In single-unit mode anything following the first
+// value is reported once as unexpected.
+func scanDuration(input string, d *directive, parseMode comptime.ParseMode, extended bool) (time.Duration, []error) {
+	nextDigit := func(position int) int {
+		for position < len(input) && !isDigit(input[position]) {
+			position++
+		}
+		return position
+	}
+
+	var errs []error
+	var prevUnit comptime.Unit
+	haveUnit := false
+	haveNumber := false
+	total := durationTotal{max: d.max}
+
+	for position := 0; position < len(input); {
+		if extended {
+			if position = skipSeparators(input, position); position == len(input) {
+				break
+			}
+		}
+
+		if parseMode == comptime.ParseModeSingleUnit && position > 0 {
+			errs = append(errs, syntaxError(comptime.UnexpectedCharactersInSingleUnitMode, position))
+			break
+		}
+
+		numStart := position
+		var number decimal
+		var value int64
+		overflowed := false
+
+		if extended {
+			var bad int
+			if number, position, bad = scanDecimal(input, position); bad >= 0 {
+				errs = append(errs, syntaxError(comptime.InvalidNumber, bad))
+				position = nextDigit(bad)
+				continue
+			}
+		} else {
+			for position < len(input) && isDigit(input[position]) {
+				digit := int64(input[position] - '0')
+				if value > (math.MaxInt64-digit)/10 {
+					overflowed = true
+				}
+				value = value*10 + digit
+				position++
+			}
+			if position == numStart {
+				errs = append(errs, syntaxError(comptime.InvalidNumber, numStart))
+				position = nextDigit(position)
+				continue
+			}
+		}
+		numEnd := position
+		haveNumber = true
+
+		if overflowed {
+			errs = append(errs, &diagnosticError{class: "OverflowError", position: numStart})
+		}
+
+		// An extended number may be separated from its unit by
+		// blanks, and is without a unit if another number or a
+		// separator follows.
+		unit := d.defaultUnit
+		unitStart := numEnd
+		scan := scanUnit
+		if extended {
+			for unitStart < len(input) && isBlank(input[unitStart]) {
+				unitStart++
+			}
+			scan = scanUnitName
+		}
+		if unitStart < len(input) && !(extended && strings.IndexByte("0123456789,.", input[unitStart]) >= 0) {
+			var ok bool
+			if unit, position, ok = scan(input, unitStart); !ok {
+				errs = append(errs, syntaxError(comptime.InvalidUnit, unitStart))
+				position = nextDigit(unitStart)
+				continue
+			}
+		}
+
+		if overflowed {
+			continue
+		}
+
+		if haveUnit && unit >= prevUnit {
+			errs = append(errs, syntaxError(comptime.InvalidUnitOrder, unitStart))
+			continue
+		}
+		prevUnit, haveUnit = unit, true
+
+		// A plain number is checked for overflow as comptime
+		// does; an extended one is checked exactly.
+		composite := time.Duration(value) * unitDurations[unit]
+		if extended {
+			var err error
+			if composite, err = number.duration(unitDurations[unit], numStart); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := total.add(composite, numStart); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Input made only of separators has no value at all and must
+	// not be read as 0ms, which HAProxy treats as no timeout.
+	if extended && !haveNumber && len(errs) == 0 {
+		errs = append(errs, syntaxError(comptime.InvalidNumber, 0))
+	}
+
+	return total.total, errs
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// syntaxError returns a SyntaxError with the given cause at position.
+func syntaxError(cause comptime.SyntaxErrorCause, position int) error {
+	return &diagnosticError{class: "SyntaxError", cause: cause, position: position}
+}
+
+// durationTotal accumulates the values that make up a duration,
+// checking each as it is added.
+type durationTotal struct {
+	// max is the largest total allowed.
+	max time.Duration
+
+	total time.Duration
+
+	// exceeded is set once a value has taken the total beyond
+	// max, as every later value would too.
+	exceeded bool
+}
+
+// add adds value, found at position in the input, to the total. An
+// OverflowError is returned if the total could not be represented,
+// and a RangeError if it would exceed max, for the first such value
+// only; later ones are not added and nil is returned.
+func (t *durationTotal) add(value time.Duration, position int) error {
+	switch {
+	case value < 0 || t.total > math.MaxInt64-value:
+		return &diagnosticError{class: "OverflowError", position: position}
+	case t.exceeded:
+		return nil
+	case value+t.total > t.max:
+		t.exceeded = true
+		return &diagnosticError{class: "RangeError", position: position}
+	}
+	t.total += value
+	return nil
+}
+
+// joinErrors returns nil if errs is empty, the only error if there is
+// one, and otherwise all of them as parseErrors.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return parseErrors(errs)
+}
+
+// scanUnit returns the unit whose symbol starts at position in input,
+// and the position just past it, recognising the same symbols as
+// comptime.ParseDuration: "ms" and "us" before the single-character
+// "d", "h", "m" and "s".
+func scanUnit(input string, position int) (comptime.Unit, int, bool) {
+	if len(input) > position+1 && input[position+1] == 's' {
+		switch input[position] {
+		case 'm':
+			return comptime.Millisecond, position + 2, true
+		case 'u':
+			return comptime.Microsecond, position + 2, true
+		}
+	}
+
+	switch input[position] {
+	case 'd':
+		return comptime.Day, position + 1, true
+	case 'h':
+		return comptime.Hour, position + 1, true
+	case 'm':
+		return comptime.Minute, position + 1, true
+	case 's':
+		return comptime.Second, position + 1, true
+	}
+
+	return 0, position, false
+}
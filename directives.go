@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/frobware/comptime"
+)
+
+// maxSecondsTimeout is the largest value accepted by directives that
+// HAProxy stores as a signed 32-bit number of seconds.
+const maxSecondsTimeout = 2147483647 * time.Second
+
+// maxIdleTimer is the largest value HAProxy accepts for
+// tune.idletimer, which is stored in 16 bits.
+const maxIdleTimer = 65535 * time.Millisecond
+
+// Keywords that introduce lines carrying time-valued options, mapped
+// to the index of the first word that may be an option. Preceding
+// words are positional arguments, such as a server's name and
+// address, and are never mistaken for an option.
+var (
+	serverLines     = map[string]int{"server": 3, "default-server": 1, "server-template": 4}
+	cookieLines     = map[string]int{"cookie": 2}
+	stickTableLines = map[string]int{"stick-table": 1, "table": 2}
+)
+
+// directive describes an HAProxy configuration keyword that takes a
+// time value, and how HAProxy interprets that value.
+type directive struct {
+	// name is the keyword as written in the configuration, for
+	// example "timeout client", "inter" or "tune.ssl.lifetime".
+	name string
+
+	// defaultUnit is the unit HAProxy applies to a value written
+	// without one.
+	defaultUnit comptime.Unit
+
+	// min and max are the smallest and largest values HAProxy
+	// accepts.
+	min, max time.Duration
+
+	// resolution is the precision with which HAProxy stores the
+	// value, truncating anything finer. Zero means milliseconds.
+	resolution time.Duration
+
+	// options, when set, makes name an option that may appear
+	// anywhere among the arguments of lines beginning with one
+	// of the given keywords, as with server "inter". Otherwise
+	// name is matched against the leading words of a line.
+	options map[string]int
+}
+
+// defaultDirective describes how a duration is interpreted when no
+// directive is named: values without a unit are milliseconds and the
+// result must not exceed maxTimeout, as for every HAProxy timeout.
+var defaultDirective = &directive{
+	defaultUnit: comptime.Millisecond,
+	max:         maxTimeout,
+}
+
+// directives is the catalogue of HAProxy keywords that take a time
+// value.
+var directives = []*directive{
+	{name: "timeout check", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout client", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout client-fin", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout client-hs", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout connect", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout http-keep-alive", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout http-request", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout mail", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout queue", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout resolve", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout retry", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout server", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout server-fin", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout tarpit", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "timeout tunnel", defaultUnit: comptime.Millisecond, max: maxTimeout},
+
+	{name: "inter", defaultUnit: comptime.Millisecond, min: time.Millisecond, max: maxTimeout, options: serverLines},
+	{name: "fastinter", defaultUnit: comptime.Millisecond, min: time.Millisecond, max: maxTimeout, options: serverLines},
+	{name: "downinter", defaultUnit: comptime.Millisecond, min: time.Millisecond, max: maxTimeout, options: serverLines},
+	{name: "agent-inter", defaultUnit: comptime.Millisecond, min: time.Millisecond, max: maxTimeout, options: serverLines},
+	{name: "slowstart", defaultUnit: comptime.Millisecond, max: maxTimeout, options: serverLines},
+	{name: "pool-purge-delay", defaultUnit: comptime.Millisecond, max: maxTimeout, options: serverLines},
+
+	{name: "hold nx", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "hold obsolete", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "hold other", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "hold refused", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "hold timeout", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "hold valid", defaultUnit: comptime.Millisecond, max: maxTimeout},
+
+	{name: "tcp-request inspect-delay", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "tcp-response inspect-delay", defaultUnit: comptime.Millisecond, max: maxTimeout},
+
+	{name: "hard-stop-after", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "close-spread-time", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "stats timeout", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "stats refresh", defaultUnit: comptime.Second, max: maxSecondsTimeout, resolution: time.Second},
+
+	{name: "maxidle", defaultUnit: comptime.Second, max: maxSecondsTimeout, resolution: time.Second, options: cookieLines},
+	{name: "maxlife", defaultUnit: comptime.Second, max: maxSecondsTimeout, resolution: time.Second, options: cookieLines},
+	{name: "expire", defaultUnit: comptime.Millisecond, max: maxTimeout, options: stickTableLines},
+
+	{name: "tune.idletimer", defaultUnit: comptime.Millisecond, max: maxIdleTimer},
+	{name: "tune.lua.burst-timeout", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "tune.lua.service-timeout", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "tune.lua.session-timeout", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "tune.lua.task-timeout", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "tune.quic.frontend.max-idle-timeout", defaultUnit: comptime.Millisecond, max: maxTimeout},
+	{name: "tune.ssl.lifetime", defaultUnit: comptime.Second, max: maxSecondsTimeout, resolution: time.Second},
+}
+
+// lookupDirective returns the catalogue entry for the named
+// directive.
+func lookupDirective(name string) (*directive, error) {
+	for _, d := range directives {
+		if d.name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown directive %q", name)
+}
+
+// minimumError reports a value that is smaller than the smallest
+// value HAProxy accepts for a directive. It applies to the value as
+// a whole, so its position is always the start of the input.
+type minimumError struct {
+	directive *directive
+}
+
+// Error returns a message naming the directive and its minimum.
+func (e *minimumError) Error() string {
+	return fmt.Sprintf("range error: %s must be at least %s", e.directive.name, formatDuration(e.directive.min))
+}
+
+// Position returns the 0-based position of the error in the input,
+// which is always 0.
+func (e *minimumError) Position() int {
+	return 0
+}
+
+// timeValue is a time-valued argument found on a configuration line.
+type timeValue struct {
+	// directive is the catalogue entry for the keyword that
+	// takes the value.
+	directive *directive
+
+	// token is the argument holding the time value.
+	token configToken
+
+	// extra, when set, is the first of any words following the
+	// value of a directive that takes a single argument, which
+	// HAProxy rejects.
+	extra *configToken
+}
+
+// findTimeValues returns every time-valued argument on the given
+// configuration line, in the order they appear. A directive that is
+// missing its value is returned with a zero-length token positioned
+// at the end of the line, and one followed by further words records
+// the first of them as extra.
+func findTimeValues(line configLine) []timeValue {
+	tokens := line.tokens
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	valueAt := func(d *directive, i int) timeValue {
+		if i >= len(tokens) {
+			end := len(line.text)
+			return timeValue{directive: d, token: configToken{start: end, end: end}}
+		}
+		return timeValue{directive: d, token: tokens[i]}
+	}
+
+	var values []timeValue
+
+	for _, d := range directives {
+		if d.options != nil {
+			first, ok := d.options[tokens[0].text]
+			if !ok {
+				continue
+			}
+			for i := first; i < len(tokens); i++ {
+				if tokens[i].text == d.name {
+					values = append(values, valueAt(d, i+1))
+					i++
+				}
+			}
+			continue
+		}
+
+		words := strings.Fields(d.name)
+		if len(tokens) < len(words) {
+			continue
+		}
+		matched := true
+		for i, word := range words {
+			if tokens[i].text != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			tv := valueAt(d, len(words))
+			if len(tokens) > len(words)+1 {
+				tv.extra = &tokens[len(words)+1]
+			}
+			values = append(values, tv)
+		}
+	}
+
+	// Options are gathered per directive; report them in the
+	// order they are written.
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].token.start < values[j].token.start
+	})
+
+	return values
+}
+
+// errMissingTimeValue is reported when a time-valued directive has no
+// argument, or an empty one such as "", which HAProxy also refuses.
+var errMissingTimeValue = errors.New("missing time value")
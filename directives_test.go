@@ -0,0 +1,56 @@
+package main_test
+
+import (
+	"reflect"
+	"testing"
+
+	cmd "github.com/frobware/haproxytime"
+)
+
+func TestConfigTimeValues(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		expected    []string
+	}{{
+		description: "timeouts",
+		input:       "timeout client 30s\ntimeout server\n",
+		expected:    []string{"timeout client=30s", "timeout server="},
+	}, {
+		description: "server options in the order written",
+		input:       "server s1 10.0.0.1:80 check slowstart 1m inter 2s fastinter 500 downinter 5s\n",
+		expected:    []string{"slowstart=1m", "inter=2s", "fastinter=500", "downinter=5s"},
+	}, {
+		description: "server names are not options",
+		input:       "server inter 10.0.0.1:80 inter 2s\n",
+		expected:    []string{"inter=2s"},
+	}, {
+		description: "default-server options",
+		input:       "default-server inter 3s\n",
+		expected:    []string{"inter=3s"},
+	}, {
+		description: "cookie and stick-table options",
+		input:       "cookie SRV insert maxidle 30m maxlife 8h\nstick-table type ip size 1m expire 30s\n",
+		expected:    []string{"maxidle=30m", "maxlife=8h", "expire=30s"},
+	}, {
+		description: "multi-word directives",
+		input:       "hold valid 10s\ntcp-request inspect-delay 5s\nstats refresh 10\n",
+		expected:    []string{"hold valid=10s", "tcp-request inspect-delay=5s", "stats refresh=10"},
+	}, {
+		description: "global tunables",
+		input:       "hard-stop-after 30m\ntune.ssl.lifetime 300\n",
+		expected:    []string{"hard-stop-after=30m", "tune.ssl.lifetime=300"},
+	}, {
+		description: "unrelated keywords",
+		input:       "maxconn 100\ntimeout bogus 1s\ntcp-request content accept\n",
+		expected:    nil,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			if got := cmd.ConfigTimeValues(tc.input); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,29 @@ package main
 
 // Export for unit testing purposes.
 var (
+	CaretLine            = caretLine
 	ConvertDuration      = convertDuration
 	PrintPositionalError = printPositionalError
 )
+
+// ConfigWords returns the words on each line of the HAProxy
+// configuration in data, as seen after quote and escape processing.
+func ConfigWords(data string) [][]string {
+	var words [][]string
+	for _, line := range parseConfig(data) {
+		words = append(words, line.words())
+	}
+	return words
+}
+
+// ConfigTimeValues returns each time value found in the HAProxy
+// configuration in data as "<directive>=<value>".
+func ConfigTimeValues(data string) []string {
+	var values []string
+	for _, line := range parseConfig(data) {
+		for _, tv := range findTimeValues(line) {
+			values = append(values, tv.directive.name+"="+tv.token.text)
+		}
+	}
+	return values
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/frobware/comptime"
+)
+
+// The causes of a syntax error in an arithmetic expression, such as
+// "2h - 15m" or "max(30s, 2 * 10s)".
+const (
+	// invalidOperator is the cause for anything other than an
+	// operator, a closing parenthesis or a comma after a value.
+	invalidOperator = invalidClockField + 1 + iota
+
+	// unbalancedParenthesis is the cause for an opening
+	// parenthesis that is never closed, or a closing one that was
+	// never opened.
+	unbalancedParenthesis
+
+	// unknownFunction is the cause for a call to a function other
+	// than those in exprFunctions.
+	unknownFunction
+
+	// durationProduct is the cause for the product of two
+	// durations, such as "2s * 3s", which is not a duration.
+	durationProduct
+
+	// durationDivisor is the cause for a division by a duration,
+	// such as "1h / 30m", which is not a duration.
+	durationDivisor
+
+	// divisionByZero is the cause for a division by zero.
+	divisionByZero
+)
+
+// exprFunctions maps the functions an expression may call to the sign
+// of the comparison that selects their result from their arguments:
+// max returns the largest argument and min the smallest.
+var exprFunctions = map[string]int{
+	"max": 1,
+	"min": -1,
+}
+
+// isExpression reports whether input is an arithmetic expression: it
+// has an operator or parenthesis that is not part of a number, such
+// as the "-" of "2h - 15m" but not that of "1.5e-3s".
+func isExpression(input string) bool {
+	for position := 0; position < len(input); position++ {
+		c := input[position]
+		if c >= '0' && c <= '9' {
+			if _, end, bad := scanDecimal(input, position); bad < 0 {
+				position = end - 1
+			}
+			continue
+		}
+		if strings.IndexByte("+-*/()", c) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// exprValue is the value of an expression or of one of its operands.
+type exprValue struct {
+	// value is the number, or the duration in nanoseconds. It is
+	// nil if a problem has already been reported for the value.
+	value *big.Rat
+
+	// scalar is true for a plain number, such as the "3" of
+	// "3 * 30s", and false for a duration.
+	scalar bool
+
+	// position is the 0-based byte offset of the value in the
+	// input.
+	position int
+
+	// operator is the position of the last operator that
+	// produced a plain number from two others, such as the "-"
+	// of "10 - 20", or 0 if there is none, as no operator can
+	// come first. A problem with the number once read as a
+	// duration is reported there.
+	operator int
+}
+
+// exprParser evaluates an expression as it parses it, by recursive
+// descent, recording every problem found in errs.
+type exprParser struct {
+	input    string
+	position int
+	d        *directive
+	errs     []error
+
+	// stopped is set once a problem with the structure of the
+	// expression has been found.
+	stopped bool
+}
+
+// scanExpression evaluates input as an arithmetic expression, such as
+// "2h - 15m", "3 * 30s", "(1m30s) / 2" or "max(30s, 2 * 10s)", and
+// returns the result along with every problem found, in order of
+// position, as scanDuration does.
+//
+// The operators are +, -, * and /, with the usual precedence, and
+// parentheses; max and min return the largest and smallest of their
+// arguments. Each operand is either a plain number or a duration
+// written as scanDuration accepts it. A duration may be multiplied or
+// divided by a number, but not by another duration. A number added
+// to or compared with a duration, or that is the whole expression, is
+// read in d's default unit, as HAProxy reads a value without a unit.
+//
+// Arithmetic is exact. Every operand and every intermediate duration
+// is checked as a value of its own: it must not overflow, must be
+// within 0 and d.max, and must be a whole number of microseconds. A
+// problem with an intermediate duration is reported at the operator,
+// or function, that produced it. Parsing stops at the first problem
+// with the structure of the expression.
+func scanExpression(input string, d *directive) (time.Duration, []error) {
+	p := &exprParser{input: input, d: d}
+
+	result := p.parseSum()
+	p.skipBlanks()
+	if p.position < len(input) {
+		cause := invalidOperator
+		if input[p.position] == ')' {
+			cause = unbalancedParenthesis
+		}
+		p.syntaxError(cause, p.position)
+	}
+	result = p.duration(result, -1)
+
+	sort.SliceStable(p.errs, func(i, j int) bool {
+		return errorPosition(p.errs[i]) < errorPosition(p.errs[j])
+	})
+	if len(p.errs) > 0 || result.value == nil {
+		return 0, p.errs
+	}
+
+	return time.Duration(result.value.Num().Int64()), nil
+}
+
+// syntaxError records a syntax error with cause at position. A
+// problem with the structure of the expression also ends parsing, as
+// nothing after it can be relied upon.
+func (p *exprParser) syntaxError(cause comptime.SyntaxErrorCause, position int) {
+	p.errs = append(p.errs, &diagnosticError{class: "SyntaxError", cause: cause, position: position})
+	switch cause {
+	case comptime.InvalidNumber, invalidOperator, unbalancedParenthesis, unknownFunction:
+		p.position = len(p.input)
+		p.stopped = true
+	}
+}
+
+// skipBlanks advances past any blanks.
+func (p *exprParser) skipBlanks() {
+	for p.position < len(p.input) && isBlank(p.input[p.position]) {
+		p.position++
+	}
+}
+
+// parseSum parses a sequence of products separated by + or -.
+func (p *exprParser) parseSum() exprValue {
+	left := p.parseProduct()
+	for {
+		p.skipBlanks()
+		if p.position == len(p.input) || (p.input[p.position] != '+' && p.input[p.position] != '-') {
+			return left
+		}
+		op, opPos := p.input[p.position], p.position
+		p.position++
+		right := p.parseProduct()
+
+		if left.value == nil || right.value == nil {
+			left = exprValue{position: left.position}
+			continue
+		}
+		if left.scalar && right.scalar {
+			left.value = applyOperator(op, left.value, right.value)
+			left.operator = opPos
+			continue
+		}
+		left = p.duration(left, opPos)
+		right = p.duration(right, opPos)
+		if left.value == nil || right.value == nil {
+			left = exprValue{position: left.position}
+			continue
+		}
+		left = p.check(exprValue{value: applyOperator(op, left.value, right.value), position: left.position}, opPos)
+	}
+}
+
+// parseProduct parses a sequence of factors separated by * or /.
+func (p *exprParser) parseProduct() exprValue {
+	left := p.parseFactor()
+	for {
+		p.skipBlanks()
+		if p.position == len(p.input) || (p.input[p.position] != '*' && p.input[p.position] != '/') {
+			return left
+		}
+		op, opPos := p.input[p.position], p.position
+		p.position++
+		right := p.parseFactor()
+
+		if left.value == nil || right.value == nil {
+			left = exprValue{position: left.position}
+			continue
+		}
+
+		switch {
+		case op == '*' && !left.scalar && !right.scalar:
+			p.syntaxError(durationProduct, right.position)
+		case op == '/' && !right.scalar:
+			p.syntaxError(durationDivisor, right.position)
+		case op == '/' && right.value.Sign() == 0:
+			p.syntaxError(divisionByZero, right.position)
+		default:
+			result := exprValue{value: applyOperator(op, left.value, right.value), scalar: left.scalar && right.scalar, position: left.position}
+			if result.scalar {
+				result.operator = opPos
+			} else {
+				result = p.check(result, opPos)
+			}
+			left = result
+			continue
+		}
+		left = exprValue{position: left.position}
+	}
+}
+
+// parseFactor parses an operand, a parenthesised expression or a
+// function call.
+func (p *exprParser) parseFactor() exprValue {
+	p.skipBlanks()
+	start := p.position
+	switch {
+	case start == len(p.input):
+		p.syntaxError(comptime.InvalidNumber, start)
+		return exprValue{position: start}
+
+	case p.input[start] == '(':
+		p.position++
+		value := p.parseSum()
+		p.skipBlanks()
+		if p.position < len(p.input) && p.input[p.position] == ')' {
+			p.position++
+		} else if p.position == len(p.input) && !p.stopped {
+			p.syntaxError(unbalancedParenthesis, start)
+		}
+		value.position = start
+		return value
+
+	case isLetter(p.input[start]):
+		for p.position < len(p.input) && isLetter(p.input[p.position]) {
+			p.position++
+		}
+		name := p.input[start:p.position]
+		p.skipBlanks()
+		if p.position == len(p.input) || p.input[p.position] != '(' {
+			p.syntaxError(comptime.InvalidNumber, start)
+			return exprValue{position: start}
+		}
+		sign, ok := exprFunctions[name]
+		if !ok {
+			p.syntaxError(unknownFunction, start)
+			return exprValue{position: start}
+		}
+		return p.parseCall(sign, start)
+	}
+
+	return p.parseOperand()
+}
+
+// parseCall parses the parenthesised arguments of the function named
+// at start, whose opening parenthesis is at p.position, and returns
+// the argument selected by sign as exprFunctions describes.
+func (p *exprParser) parseCall(sign int, start int) exprValue {
+	open := p.position
+	p.position++
+
+	result := exprValue{position: start}
+	valid := true
+	for {
+		arg := p.duration(p.parseSum(), -1)
+		if arg.value == nil {
+			valid = false
+		} else if result.value == nil || arg.value.Cmp(result.value)*sign > 0 {
+			result.value = arg.value
+		}
+
+		p.skipBlanks()
+		if p.position == len(p.input) {
+			if !p.stopped {
+				p.syntaxError(unbalancedParenthesis, open)
+			}
+			return exprValue{position: start}
+		}
+		switch p.input[p.position] {
+		case ',':
+			p.position++
+			continue
+		case ')':
+			p.position++
+		default:
+			p.syntaxError(invalidOperator, p.position)
+			return exprValue{position: start}
+		}
+		break
+	}
+
+	if !valid {
+		return exprValue{position: start}
+	}
+	return result
+}
+
+// parseOperand parses a plain number, such as "3", or a duration,
+// such as "30s" or "2 hours 30 minutes", which extends over every
+// number and unit name separated only by blanks.
+func (p *exprParser) parseOperand() exprValue {
+	isNumber := func(c byte) bool {
+		return isDigit(c) || c == '.'
+	}
+
+	start := p.position
+	if !isNumber(p.input[start]) {
+		p.syntaxError(comptime.InvalidNumber, start)
+		return exprValue{position: start}
+	}
+
+	var number decimal
+	numbers := 0
+	unit := false
+	end := start
+	for {
+		var bad int
+		number, end, bad = scanDecimal(p.input, p.position)
+		if bad >= 0 && p.input[bad] == ',' {
+			// The comma separates the arguments of a
+			// call, as in "max(3, 2)", rather than
+			// grouping digits.
+			number, end, bad = scanDecimal(p.input[:bad], p.position)
+		}
+		if bad >= 0 {
+			p.syntaxError(comptime.InvalidNumber, bad)
+			return exprValue{position: start}
+		}
+		numbers++
+
+		p.position = end
+		p.skipBlanks()
+		if p.position < len(p.input) && isLetter(p.input[p.position]) {
+			for p.position < len(p.input) && isLetter(p.input[p.position]) {
+				p.position++
+			}
+			end = p.position
+			unit = true
+			p.skipBlanks()
+		}
+		if p.position == len(p.input) || !isNumber(p.input[p.position]) {
+			break
+		}
+	}
+	p.position = end
+
+	if numbers == 1 && !unit {
+		return exprValue{value: number.rat(), scalar: true, position: start}
+	}
+
+	duration, errs := scanDuration(p.input[start:end], p.d, comptime.ParseModeMultiUnit, true)
+	for _, err := range errs {
+		if diagErr, ok := err.(*diagnosticError); ok {
+			diagErr.position += start
+		}
+		p.errs = append(p.errs, err)
+	}
+	if len(errs) > 0 {
+		return exprValue{position: start}
+	}
+
+	return exprValue{value: new(big.Rat).SetInt64(int64(duration)), position: start}
+}
+
+// duration returns v as a duration, reading a plain number in the
+// default unit of p.d and checking it as check does, at position if
+// it is not negative and otherwise at the operator that produced the
+// number or, failing that, the number itself.
+func (p *exprParser) duration(v exprValue, position int) exprValue {
+	if v.value == nil || !v.scalar {
+		return v
+	}
+	if position < 0 {
+		position = v.position
+		if v.operator > 0 {
+			position = v.operator
+		}
+	}
+
+	unit := new(big.Rat).SetInt64(int64(unitDurations[p.d.defaultUnit]))
+	return p.check(exprValue{value: new(big.Rat).Mul(v.value, unit), position: v.position}, position)
+}
+
+// check returns v if it is a valid duration, or records the problem
+// with it at position and returns an invalid value.
+func (p *exprParser) check(v exprValue, position int) exprValue {
+	limit := new(big.Rat).SetInt64(math.MaxInt64)
+	micros := new(big.Rat).Quo(v.value, new(big.Rat).SetInt64(int64(time.Microsecond)))
+
+	switch {
+	case new(big.Rat).Abs(v.value).Cmp(limit) > 0:
+		p.errs = append(p.errs, &diagnosticError{class: "OverflowError", position: position})
+	case v.value.Sign() < 0 || v.value.Cmp(new(big.Rat).SetInt64(int64(p.d.max))) > 0:
+		p.errs = append(p.errs, &diagnosticError{class: "RangeError", position: position})
+	case !micros.IsInt():
+		p.syntaxError(inexactFraction, position)
+	default:
+		return v
+	}
+
+	return exprValue{position: v.position}
+}
+
+// applyOperator returns the result of applying op, one of "+-*/", to
+// x and y. y must not be zero for "/".
+func applyOperator(op byte, x, y *big.Rat) *big.Rat {
+	result := new(big.Rat)
+	switch op {
+	case '+':
+		result.Add(x, y)
+	case '-':
+		result.Sub(x, y)
+	case '*':
+		result.Mul(x, y)
+	case '/':
+		result.Quo(x, y)
+	}
+	return result
+}
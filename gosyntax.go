@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/frobware/comptime"
+)
+
+// goUnits maps the units accepted by time.ParseDuration to the
+// duration they stand for.
+var goUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond, // U+00B5 MICRO SIGN
+	"μs": time.Microsecond, // U+03BC GREEK SMALL LETTER MU
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// parseGoDuration parses input with time.ParseDuration and checks the
+// result against the maximum of directive d. Negative values are
+// rejected as out of range, as a timeout cannot be negative. When
+// time.ParseDuration rejects input, the error returned locates the
+// problem as goSyntaxError does.
+func parseGoDuration(input string, d *directive) (time.Duration, error) {
+	duration, err := time.ParseDuration(input)
+	if err != nil {
+		return 0, goSyntaxError(input)
+	}
+
+	if duration < 0 || duration > d.max {
+		return 0, &diagnosticError{class: "RangeError", position: 0}
+	}
+
+	return duration, nil
+}
+
+// goSyntaxError returns the first problem in input, which
+// time.ParseDuration has rejected, as a positional error: an invalid
+// number, a missing or unknown unit, or a value too large for a
+// time.Duration.
+func goSyntaxError(input string) error {
+	position := 0
+	negative := false
+	if position < len(input) && (input[position] == '-' || input[position] == '+') {
+		negative = input[position] == '-'
+		position++
+	}
+	if position == len(input) {
+		return syntaxError(comptime.InvalidNumber, position)
+	}
+
+	// time.ParseDuration accepts values up to 1<<63 nanoseconds,
+	// truncating fractions of a nanosecond, and one more when
+	// negative.
+	limit := new(big.Int).SetUint64(math.MaxInt64)
+	if negative {
+		limit.Add(limit, big.NewInt(1))
+	}
+	total := new(big.Int)
+
+	for position < len(input) {
+		numStart := position
+		for position < len(input) && isDigit(input[position]) {
+			position++
+		}
+		digits := input[numStart:position]
+		fraction := ""
+		if position < len(input) && input[position] == '.' {
+			position++
+			fracStart := position
+			for position < len(input) && isDigit(input[position]) {
+				position++
+			}
+			fraction = input[fracStart:position]
+		}
+		if digits == "" && fraction == "" {
+			return syntaxError(comptime.InvalidNumber, numStart)
+		}
+
+		// The unit is the longest one in goUnits. A letter
+		// after it makes the whole word an unknown unit, as in
+		// "1hours"; anything else must start the next number.
+		unitStart := position
+		unit, ok := time.Duration(0), false
+		for symbol, d := range goUnits {
+			if strings.HasPrefix(input[unitStart:], symbol) && unitStart+len(symbol) > position {
+				unit, ok, position = d, true, unitStart+len(symbol)
+			}
+		}
+		if !ok || position < len(input) && (isLetter(input[position]) || input[position] >= utf8.RuneSelf) {
+			return syntaxError(comptime.InvalidUnit, unitStart)
+		}
+		if position < len(input) && input[position] != '.' && !isDigit(input[position]) {
+			return syntaxError(comptime.InvalidNumber, position)
+		}
+
+		value, _ := new(big.Int).SetString(digits+fraction, 10)
+		value.Mul(value, big.NewInt(int64(unit)))
+		value.Quo(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(fraction))), nil))
+		if total.Add(total, value); total.Cmp(limit) > 0 {
+			return &diagnosticError{class: "OverflowError", position: numStart}
+		}
+	}
+
+	// Not expected: time.ParseDuration rejected input for a
+	// reason not found above.
+	return syntaxError(comptime.InvalidNumber, 0)
+}
+
+// formatGoDuration returns duration as time.Duration's String method
+// does, for example "1h30m0s", the form Go programs and Kubernetes
+// print. Any sub-millisecond component is ignored.
+func formatGoDuration(duration time.Duration) string {
+	return (duration - duration%time.Millisecond).String()
+}
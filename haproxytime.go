@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"flag"
 	"fmt"
@@ -42,14 +43,89 @@ haproxytime - Convert human-readable time duration to millisecond format
 
 General Usage:
   haproxytime [-help] [-v]
-  haproxytime [-h] [-m] [<duration>]
+  haproxytime [-h] [-o <format>] [-m] [-q] [-strict] [-lenient] [-fix] [-round <mode>] [-directive <name>] [-syntax <name>] [<duration>...]
+  haproxytime -batch [-continue] [-max-bytes <n>] [<flags>] < <file>
+  haproxytime lint [-composite] [<file>...]
+  haproxytime resolve [<file>...]
+  haproxytime rewrite [-diff] [-backup <suffix>] [-round <mode>] [-o <format>] [<file>...]
 
 Usage:
   -help Show usage information
   -v	Show version information
   -h	Print duration value in a human-readable format
+  -o <format>
+	Output format: ms (default), human (same as -h), unit,
+	iso8601, go, clock or json. unit prints the largest single
+	unit that represents the value exactly, for example 90s
+	rather than 90000ms or 1m30s, and is valid HAProxy syntax.
+	iso8601 prints an ISO 8601 duration, for example PT1M30S.
+	go prints the form of Go's time.Duration, for example 1m30s
+	or 1h30m0s, as used by Kubernetes metav1.Duration. clock
+	prints a clock time, for example 00:01:30 or 1d 04:00:00.500.
+	json prints an object per value on stdout, including values
+	that fail, with the input, milliseconds, human form and
+	per-unit breakdown, or an error with its class (SyntaxError,
+	OverflowError, RangeError, ...), cause and 0-based byte
+	position.
   -m	Print the maximum HAProxy timeout value
-  <duration>: value to convert. If omitted, will read from stdin.
+  -q	Validate only: print nothing and report the result in the
+	exit status.
+  -batch
+	Read durations from stdin, one per line, converting each
+	line independently and writing one result line as each
+	input line is read. Errors are reported as
+	<stdin>:line:column. Blank lines are rejected. Stops at
+	the first line that fails unless -continue is given.
+  -continue
+	In batch mode, keep converting after a line fails; the exit
+	status is still that of the first line that failed.
+  -max-bytes <n>
+	Maximum length in bytes of a duration read from stdin, or of
+	each line in batch mode (default 256). Longer input is
+	rejected with an error rather than truncated.
+  -strict
+	Accept only the time syntax HAProxy itself accepts in its
+	configuration: a single number with an optional unit, such
+	as 90s, rather than a composite such as 1m30s. When a value
+	is rejected the equivalent HAProxy error is also printed.
+  -lenient
+	Accept units in any order, and repeated units, summing
+	them: 30m2h is read as 2h30m and 1h1h as 2h. A warning shows
+	the canonical form. Without -lenient such values are
+	rejected, with the canonical form shown after the error.
+	Ignored with -strict.
+  -fix	Convert a value written with common but invalid units,
+	such as 1w2d, 1500µs or 2000ns, as if it had been written
+	in HAProxy's syntax, printing a warning with the corrected
+	value. Without -fix the correction is suggested after the
+	error, for example "did you mean 9d?".
+  -round <mode>
+	How to reduce a value that is not a whole number of
+	milliseconds: down (default, as HAProxy does), up, nearest,
+	or error to reject it. A warning is printed whenever
+	precision is lost, and a non-zero value that rounds to 0ms is
+	always rejected because HAProxy treats 0 as no timeout.
+  -directive <name>
+	Interpret the duration as HAProxy does for the named
+	directive, using its default unit and limits. For example,
+	"tune.ssl.lifetime" treats a value without a unit as
+	seconds and "inter" rejects zero. Directives that HAProxy
+	stores in seconds are rounded to whole seconds, as -round
+	selects. -m prints the directive's maximum.
+  -syntax <name>
+	Input syntax: haproxy (default), described below, go or
+	systemd. go accepts exactly what Go's time.ParseDuration
+	accepts, as used by Kubernetes metav1.Duration: 1h30m0s,
+	1.5h, 300ns or 10µs, with no d unit and units in any order;
+	negative values are rejected. systemd accepts systemd time
+	spans, as used in unit files and timers: 1min 30s, 2weeks,
+	5sec or 1y, where a number without a unit is in seconds,
+	and infinity converts to 0ms, which HAProxy treats as no
+	timeout. Values are still checked against the HAProxy
+	maximum. Cannot be used with -strict.
+  <duration>: value to convert. Several values may be given and
+	each is converted on its own line. If omitted, will read
+	from stdin.
 
 The flags [-help] and [-v] are mutually exclusive with any other
 options or duration input.
@@ -62,13 +138,128 @@ Available units for time durations:
   ms: milliseconds
   us: microseconds
 
-A duration value without a unit defaults to milliseconds.
+A duration value without a unit defaults to milliseconds, unless
+-directive names a directive that HAProxy reads in seconds.
+
+Outside -strict, a duration may also be written out in words, as in
+"2 hours 30 minutes", "1 day, 4 hrs" or "90 seconds". Values may be
+separated by spaces, commas or "and", and units named as day(s),
+hour(s), hr(s), minute(s), min(s), second(s), sec(s),
+millisecond(s), msec(s), microsecond(s) or usec(s), in any case, as
+in "2 Hours". Quote such a value to pass it as a single argument.
+
+An ISO 8601 duration, such as PT2H30M, P1DT12H or PT0.5S, is also
+accepted outside -strict. Weeks are read as 7 days; years and months
+are rejected as their length varies.
+
+A clock time, [Nd ][H:]M:SS[.fff], such as 01:30:00, 2:05.250 or
+"1d 04:00:00", is also accepted outside -strict. Every field but the
+first must be two digits from 00 to 59.
+
+Outside -strict, a value may also be an arithmetic expression, such
+as "2h - 15m", "3 * 30s", "(1m30s) / 2" or "max(30s, 2 * 10s)", with
++, -, *, /, parentheses, and max and min of any number of values. A
+duration may be multiplied or divided by a plain number but not by
+another duration; a plain number used as a duration is read in the
+default unit. Every operand and intermediate result is checked for
+overflow and against the maximum, and must be a whole number of
+microseconds, with any problem reported at the operator responsible.
+
+Any value may have a decimal fraction, as in 1.5h or .25s, digits
+grouped with _ or , as in 2_147_483_647ms or 1,000ms, and an exponent,
+as in 1e3ms or 1.5e-3s, except with -strict. A comma must be followed
+by exactly three digits. Values are exact: one that is not a whole
+number of microseconds, such as 1.0000001s, is rejected rather than
+rounded.
+
+Every problem found in a value is reported at once, with a caret
+under each, so that a value such as 1x2h3y can be fixed in one go.
+
+Directives known to -directive:
+  timeout check, timeout client, timeout client-fin,
+  timeout client-hs, timeout connect, timeout http-keep-alive,
+  timeout http-request, timeout mail, timeout queue,
+  timeout resolve, timeout retry, timeout server,
+  timeout server-fin, timeout tarpit, timeout tunnel,
+  inter, fastinter, downinter, agent-inter, slowstart,
+  pool-purge-delay, hold nx, hold obsolete, hold other,
+  hold refused, hold timeout, hold valid,
+  tcp-request inspect-delay, tcp-response inspect-delay,
+  hard-stop-after, close-spread-time, stats timeout,
+  stats refresh (s), maxidle (s), maxlife (s), expire,
+  tune.idletimer, tune.lua.burst-timeout,
+  tune.lua.service-timeout, tune.lua.session-timeout,
+  tune.lua.task-timeout, tune.quic.frontend.max-idle-timeout,
+  tune.ssl.lifetime (s)
+Directives marked (s) read a value without a unit as seconds.
+
+Exit status:
+  0  every value was converted
+  1  unexpected error
+  2  invalid flags or arguments, or -help
+  3  stdin could not be read, or exceeded -max-bytes
+  4  syntax error
+  5  overflow: a value too large to represent
+  6  range error: a value above HAProxy's maximum, below the
+     directive's minimum, rounding to 0ms, or rejected by
+     -round error
+  7  output could not be written
+When several values fail, the status is that of the first failure.
+The subcommands exit with 1 if any problem is found.
+
+Subcommands:
+  lint  Check every time value in HAProxy configuration files,
+        reporting malformed values and values that exceed the
+        HAProxy maximum as file:line:col diagnostics. Values must
+        be valid in HAProxy's own single-unit syntax, as HAProxy
+        rejects anything else; -composite also accepts values
+        combining several units, such as 1m30s, but no other
+        extension, such as 1.5h. Reads from stdin if no file, or
+        "-", is given.
+
+  resolve
+        Print the timeouts in force in every frontend, backend and
+        listen section, following inheritance from defaults
+        sections, including named defaults and "from" clauses,
+        together with the file, line and section that set each
+        value. Values may combine several units, as in 1m30s, but
+        other extensions, such as 1.5h, are reported as problems.
+        Several files are read in order, as with HAProxy's -f
+        option. Reads from stdin if no file, or "-", is given.
+
+  rewrite
+        Convert every time value in HAProxy configuration files to
+        milliseconds, preserving everything else. Files are
+        rewritten in place after saving the original with the
+        -backup suffix (default ".bak", empty disables). With -diff
+        a unified diff is printed instead. -round selects how
+        sub-millisecond values are rounded, as above, and -o unit
+        writes each value in its largest exact single unit instead
+        of milliseconds. Reads from stdin and writes to stdout if
+        no file, or "-", is given.
 
 Examples:
   haproxytime -m           -> Print the maximum HAProxy duration.
   haproxytime 2h30m5s      -> Convert duration to milliseconds.
+  haproxytime 30s 5m 1h    -> Convert each duration to milliseconds.
+  haproxytime "2 hours, 30 minutes" -> Convert a duration written out in words.
   haproxytime -h 4500000   -> Convert 4500000ms to a human-readable format.
-  echo 150s | haproxytime  -> Convert 150 seconds to milliseconds.`[1:]
+  haproxytime -o unit 90000 -> Convert 90000ms to 90s.
+  haproxytime -o json 1m30s -> Describe 1m30s as a JSON object.
+  haproxytime -o iso8601 PT2H30M -> Convert an ISO 8601 duration and print it back.
+  haproxytime -o clock 5400000 -> Convert 5400000ms to 01:30:00.
+  haproxytime -syntax go -o go 1.5h -> Read and print Go's duration syntax.
+  haproxytime -syntax systemd "1min 30s" -> Convert a systemd time span.
+  haproxytime "2h - 15m"   -> Convert 15 minutes less than 2 hours.
+  haproxytime -q 30d       -> Exit with 6 as 30d exceeds the maximum.
+  haproxytime -fix 1w      -> Convert 1w as 7d, with a warning.
+  haproxytime -lenient 30m2h -> Convert 30m2h as 2h30m, with a warning.
+  echo 150s | haproxytime  -> Convert 150 seconds to milliseconds.
+  haproxytime -batch -continue < values.txt -> Convert a value per line.
+  haproxytime -directive tune.ssl.lifetime 300 -> Convert 300s to milliseconds.
+  haproxytime lint haproxy.cfg -> Check the time values in haproxy.cfg.
+  haproxytime resolve haproxy.cfg -> Show the timeouts of each proxy.
+  haproxytime rewrite -diff haproxy.cfg -> Show haproxy.cfg in milliseconds.`[1:]
 
 // ExitHandler defines an interface for handling exits.
 type ExitHandler interface {
@@ -83,6 +274,79 @@ func (e DefaultExitHandler) Exit(code int) {
 	os.Exit(code)
 }
 
+// Exit statuses. Each class of failure has its own status so that
+// scripts can tell why a value was rejected without parsing messages.
+// When several values fail, the status is that of the first failure.
+const (
+	// exitSuccess indicates that every value was converted.
+	exitSuccess = 0
+
+	// exitFailure indicates a failure that has no status of its
+	// own, such as an unexpected error.
+	exitFailure = 1
+
+	// exitUsage indicates invalid flags or arguments.
+	exitUsage = 2
+
+	// exitRead indicates that the input could not be read, or
+	// exceeded the -max-bytes limit.
+	exitRead = 3
+
+	// exitSyntax indicates a malformed value.
+	exitSyntax = 4
+
+	// exitOverflow indicates a value too large to be represented
+	// at all.
+	exitOverflow = 5
+
+	// exitRange indicates a value outside the range HAProxy
+	// accepts: above its maximum, below a directive's minimum,
+	// rounding to 0ms, or not a whole number of milliseconds
+	// with "-round error".
+	exitRange = 6
+
+	// exitWrite indicates that the output could not be written.
+	exitWrite = 7
+)
+
+// exitStatus returns the exit status for err, which was returned
+// while reading or converting a value. If err holds several errors
+// the status is that of the first.
+func exitStatus(err error) int {
+	err = firstError(err)
+
+	var syntaxErr *comptime.SyntaxError
+	var emptyErr *emptyValueError
+	var overflowErr *comptime.OverflowError
+	var rangeErr *comptime.RangeError
+	var minimumErr *minimumError
+	var precisionErr *precisionError
+	var underflowErr *underflowError
+	var tooLongErr *inputTooLongError
+	var diagnosticErr *diagnosticError
+
+	switch {
+	case errors.As(err, &diagnosticErr):
+		switch diagnosticErr.class {
+		case "OverflowError":
+			return exitOverflow
+		case "RangeError":
+			return exitRange
+		}
+		return exitSyntax
+	case errors.As(err, &syntaxErr), errors.As(err, &emptyErr):
+		return exitSyntax
+	case errors.As(err, &overflowErr):
+		return exitOverflow
+	case errors.As(err, &rangeErr), errors.As(err, &minimumErr), errors.As(err, &precisionErr), errors.As(err, &underflowErr):
+		return exitRange
+	case errors.As(err, &tooLongErr):
+		return exitRead
+	}
+
+	return exitFailure
+}
+
 // safeFprintf is a wrapper around fmt.Fprintf that performs a
 // formatted write operation to a given io.Writer. It takes the same
 // arguments as fmt.Fprintf: a format string and a variadic list of
@@ -93,7 +357,7 @@ func safeFprintf(w io.Writer, exitHandler ExitHandler, format string, a ...inter
 	_, err := fmt.Fprintf(w, format, a...)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error writing to output: %v\n", err)
-		exitHandler.Exit(1)
+		exitHandler.Exit(exitWrite)
 	}
 }
 
@@ -106,34 +370,54 @@ func safeFprintln(w io.Writer, exitHandler ExitHandler, a ...interface{}) {
 	_, err := fmt.Fprintln(w, a...)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error writing to output: %v\n", err)
-		exitHandler.Exit(1)
+		exitHandler.Exit(exitWrite)
 	}
 }
 
 // printErrorWithPosition writes an error message along with its
 // position in the input string to the given Writer. The function
 // prints the error, the input string, and a caret '^' pointing to the
-// position where the error occurred.
+// position where the error occurred, followed by a '~' under each
+// remaining column of the offending token. The marker is aligned by
+// display column rather than byte offset, see caretLine, so it lines
+// up with input containing tabs or multi-byte characters.
 //
 // Parameters:
 //   - w: the io.Writer to which the output is written
 //   - input: the string that produced the error
 //   - err: the error to be displayed
-//   - position: the 1-based index at which the error occurred within the input
+//   - position: the 0-based byte offset at which the error occurred
+//     within the input
 //
 // Example:
 //
-//	If the input is "24d20h31m23s647msO000us" and the error
+//	If the input is "24d20h31m23s647ms1000us" and the error
 //	occurred at position 18, the output would be:
 //
-//	syntax error at position 18: invalid number
-//	24d20h31m23s647msO000us
-//			 ^
+//	range error at position 18
+//	24d20h31m23s647ms1000us
+//	                 ^~~~~~
 func printErrorWithPosition(w io.Writer, exitHandler ExitHandler, input string, err error, position int) {
-	safeFprintln(w, exitHandler, err)
+	printErrorsWithPositions(w, exitHandler, input, []error{err}, []int{position})
+}
+
+// printErrorsWithPositions writes several errors found in the same
+// input as a single report: each error on its own line, then the
+// input, then one line marking every position as
+// printErrorWithPosition does.
+//
+// Example:
+//
+//	syntax error at position 2: invalid unit
+//	syntax error at position 6: invalid unit
+//	1x2h3y
+//	 ^   ^
+func printErrorsWithPositions(w io.Writer, exitHandler ExitHandler, input string, errs []error, positions []int) {
+	for _, err := range errs {
+		safeFprintln(w, exitHandler, err)
+	}
 	safeFprintln(w, exitHandler, input)
-	safeFprintf(w, exitHandler, "%"+fmt.Sprint(position)+"s", "")
-	safeFprintln(w, exitHandler, "^")
+	safeFprintln(w, exitHandler, caretLine(input, positions...))
 }
 
 // formatDuration takes a time.Duration value and returns a
@@ -161,6 +445,38 @@ func formatDuration(duration time.Duration) string {
 		return "0ms"
 	}
 
+	parts := splitDuration(duration)
+
+	var result string
+	if parts.days > 0 {
+		result += fmt.Sprintf("%dd", parts.days)
+	}
+	if parts.hours > 0 {
+		result += fmt.Sprintf("%dh", parts.hours)
+	}
+	if parts.minutes > 0 {
+		result += fmt.Sprintf("%dm", parts.minutes)
+	}
+	if parts.seconds > 0 {
+		result += fmt.Sprintf("%ds", parts.seconds)
+	}
+	if parts.milliseconds > 0 {
+		result += fmt.Sprintf("%dms", parts.milliseconds)
+	}
+
+	return result
+}
+
+// durationParts is a duration broken down into days, hours, minutes,
+// seconds and milliseconds.
+type durationParts struct {
+	days, hours, minutes, seconds, milliseconds int64
+}
+
+// splitDuration breaks duration down into the units HAProxy accepts,
+// from days to milliseconds. Any sub-millisecond component is
+// ignored.
+func splitDuration(duration time.Duration) durationParts {
 	const Day = time.Hour * 24
 	days := duration / Day
 	duration -= days * Day
@@ -172,50 +488,157 @@ func formatDuration(duration time.Duration) string {
 	duration -= seconds * time.Second
 	milliseconds := duration / time.Millisecond
 
-	var result string
-	if days > 0 {
-		result += fmt.Sprintf("%dd", days)
-	}
-	if hours > 0 {
-		result += fmt.Sprintf("%dh", hours)
-	}
-	if minutes > 0 {
-		result += fmt.Sprintf("%dm", minutes)
+	return durationParts{
+		days:         int64(days),
+		hours:        int64(hours),
+		minutes:      int64(minutes),
+		seconds:      int64(seconds),
+		milliseconds: int64(milliseconds),
 	}
-	if seconds > 0 {
-		result += fmt.Sprintf("%ds", seconds)
+}
+
+// formatSingleUnit returns duration in the largest single unit that
+// represents it exactly, for example "90s" for 90000ms or "1d" for
+// 86400000ms. Unlike formatDuration, the result is always valid
+// HAProxy syntax. Any sub-millisecond component is ignored.
+//
+// Example:
+//
+//	Input: 1500ms
+//	Output: "1500ms"
+//
+//	Input: 2h
+//	Output: "2h"
+func formatSingleUnit(duration time.Duration) string {
+	duration -= duration % time.Millisecond
+
+	units := []struct {
+		symbol   string
+		duration time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
 	}
-	if milliseconds > 0 {
-		result += fmt.Sprintf("%dms", milliseconds)
+
+	if duration != 0 {
+		for _, unit := range units {
+			if duration%unit.duration == 0 {
+				return fmt.Sprintf("%d%s", duration/unit.duration, unit.symbol)
+			}
+		}
 	}
 
-	return result
+	return formatMilliseconds(duration)
 }
 
-// output writes a time.Duration value to the given io.Writer. The
-// format of the output depends on the printHuman flag.
+// outputFormat selects how a converted duration is printed.
+type outputFormat int
+
+const (
+	// formatMs prints the duration in milliseconds, for example
+	// "90000ms".
+	formatMs outputFormat = iota
+
+	// formatHuman prints the duration broken down into units,
+	// for example "1m30s", as formatDuration does.
+	formatHuman
+
+	// formatUnit prints the duration in the largest single unit
+	// that represents it exactly, for example "90s", as
+	// formatSingleUnit does.
+	formatUnit
+
+	// formatJSON prints a JSON object for each value, describing
+	// either the converted duration or why it could not be
+	// converted.
+	formatJSON
+
+	// formatISO8601 prints the duration as an ISO 8601 duration,
+	// for example "PT1M30S", as formatISO8601Duration does.
+	formatISO8601
+
+	// formatGo prints the duration as Go's time.Duration does,
+	// for example "1m30s", as formatGoDuration does.
+	formatGo
+
+	// formatClock prints the duration as a clock time, for
+	// example "00:01:30", as formatClockTime does.
+	formatClock
+)
+
+// outputFormats maps the names accepted by the -o flag to their
+// output formats.
+var outputFormats = map[string]outputFormat{
+	"ms":      formatMs,
+	"human":   formatHuman,
+	"unit":    formatUnit,
+	"json":    formatJSON,
+	"iso8601": formatISO8601,
+	"go":      formatGo,
+	"clock":   formatClock,
+}
+
+// String returns the name of the output format as accepted by the -o
+// flag.
+func (f *outputFormat) String() string {
+	return flagName(outputFormats, *f)
+}
+
+// Set implements flag.Value, allowing an output format to be used
+// directly as a flag.
+func (f *outputFormat) Set(s string) error {
+	return setFlag(outputFormats, s, f)
+}
+
+// format returns duration formatted according to f.
+func (f outputFormat) format(duration time.Duration) string {
+	switch f {
+	case formatHuman:
+		return formatDuration(duration)
+	case formatUnit:
+		return formatSingleUnit(duration)
+	case formatISO8601:
+		return formatISO8601Duration(duration)
+	case formatGo:
+		return formatGoDuration(duration)
+	case formatClock:
+		return formatClockTime(duration)
+	default:
+		return formatMilliseconds(duration)
+	}
+}
+
+// output writes a time.Duration value to the given io.Writer in the
+// given format.
 //
 // Parameters:
 //   - w: the io.Writer to which the output is written
 //   - duration: the time.Duration value to be displayed
-//   - printHuman: a boolean flag; if true display in human-readable format
-//
-// If printHuman is true, the duration is formatted using the
-// formatDuration function, which breaks down the duration into units
-// like days, hours, minutes, etc., and displays it accordingly.
-//
-// If printHuman is false, the duration is simply displayed as the
-// number of milliseconds, followed by the unit "ms".
+//   - format: the outputFormat used to display the value
 //
 // Examples:
-//   - With printHuman=true and duration=86400000ms, the output will be "1d".
-//   - With printHuman=false and duration=86400000ms, the output will be "86400000ms".
-func output(w io.Writer, exitHandler ExitHandler, duration time.Duration, printHuman bool) {
-	if printHuman {
-		safeFprintln(w, exitHandler, formatDuration(duration))
-	} else {
-		safeFprintf(w, exitHandler, "%vms\n", duration.Milliseconds())
+//   - With formatHuman and duration=86400000ms, the output will be "1d".
+//   - With formatMs and duration=86400000ms, the output will be "86400000ms".
+//   - With formatUnit and duration=90000ms, the output will be "90s".
+//   - With formatISO8601 and duration=90000ms, the output will be
+//     "PT1M30S".
+//   - With formatJSON the output is a JSON object, as written for a
+//     converted value but with an empty input.
+func output(w io.Writer, exitHandler ExitHandler, duration time.Duration, format outputFormat) {
+	if format == formatJSON {
+		writeJSON(w, exitHandler, newJSONResult("", duration, nil, parseOptions{}))
+		return
 	}
+	safeFprintln(w, exitHandler, format.format(duration))
+}
+
+// formatMilliseconds returns duration as a whole number of
+// milliseconds followed by the unit "ms", for example "86400000ms".
+// This is the canonical form HAProxy accepts for every timeout.
+func formatMilliseconds(duration time.Duration) string {
+	return fmt.Sprintf("%vms", duration.Milliseconds())
 }
 
 // printPositionalError formats and outputs an error message to the
@@ -243,34 +666,198 @@ func printPositionalError(w io.Writer, exitHandler ExitHandler, err error, arg s
 		Position() int
 	}
 	if errors.As(err, &posErr) && posErr != nil {
-		printErrorWithPosition(w, exitHandler, arg, err, posErr.Position())
+		errs := splitErrors(err)
+		positions := make([]int, len(errs))
+		for i, err := range errs {
+			positions[i] = errorPosition(err)
+		}
+		printErrorsWithPositions(w, exitHandler, arg, errs, positions)
 		return
 	}
 
 	// Handle unexpected error types more gracefully.
 	safeFprintf(w, exitHandler, "Unexpected error: %v\n", err)
-	exitHandler.Exit(1)
+	exitHandler.Exit(exitFailure)
+}
+
+// inputTooLongError reports input that exceeds the maximum number of
+// bytes haproxytime is prepared to read for a single duration.
+type inputTooLongError struct {
+	limit int64
 }
 
-// readAll reads all available bytes up to maxBytes from the given
-// io.Reader into a string. It also trims any trailing newline
-// characters. If an error occurs during the read operation, it
-// returns an empty string and the error wrapped with additional
-// context.
+// Error returns a message that includes the byte limit.
+func (e *inputTooLongError) Error() string {
+	return fmt.Sprintf("input exceeds the %d byte limit set by -max-bytes", e.limit)
+}
+
+// printLineError writes err, which occurred while converting line
+// lineNumber of stdin, as "<stdin>:line:column: error" followed by
+// the line and a caret pointing at the column, as
+// printErrorWithPosition does. Errors without a position are written
+// as "<stdin>:line: error" on their own.
+func printLineError(w io.Writer, exitHandler ExitHandler, err error, lineNumber int, line string) {
+	var posErr interface {
+		Position() int
+	}
+	if errors.As(err, &posErr) && posErr != nil {
+		errs := splitErrors(err)
+		positions := make([]int, len(errs))
+		for i, err := range errs {
+			positions[i] = errorPosition(err)
+			errs[i] = fmt.Errorf("<stdin>:%d:%d: %w", lineNumber, positions[i]+1, err)
+		}
+		printErrorsWithPositions(w, exitHandler, line, errs, positions)
+		return
+	}
+
+	safeFprintf(w, exitHandler, "<stdin>:%d: %v\n", lineNumber, err)
+}
+
+// printStdinError writes err, which occurred while converting input
+// read from stdin. Input on a single line is reported exactly as a
+// command-line argument is, by printPositionalError. If the input
+// spans several lines only the line containing the error is shown,
+// using printLineError, so that the caret lines up with it; each of
+// several errors is reported against its own line.
+func printStdinError(w io.Writer, exitHandler ExitHandler, err error, input string) {
+	var posErr interface {
+		Position() int
+	}
+	if !strings.Contains(input, "\n") || !errors.As(err, &posErr) || posErr == nil {
+		printPositionalError(w, exitHandler, err, input)
+		return
+	}
+
+	lines := strings.Split(input, "\n")
+	for _, err := range splitErrors(err) {
+		position := errorPosition(err)
+		lineStart := 0
+		for lineNumber, line := range lines {
+			if position <= lineStart+len(line) {
+				line = strings.TrimSuffix(line, "\r")
+				printLineError(w, exitHandler, &linePositionError{err: err, position: position - lineStart}, lineNumber+1, line)
+				break
+			}
+			lineStart += len(line) + 1
+		}
+	}
+}
+
+// linePositionError wraps an error whose position is relative to the
+// whole input, replacing the position with one relative to the start
+// of the line containing it.
+type linePositionError struct {
+	err      error
+	position int
+}
+
+// Error returns the message of the wrapped error, with the position
+// it reports made relative to the line.
+func (e *linePositionError) Error() string {
+	old := fmt.Sprintf("at position %d", errorPosition(e.err)+1)
+	return strings.Replace(e.err.Error(), old, fmt.Sprintf("at position %d", e.position+1), 1)
+}
+
+// Unwrap returns the wrapped error.
+func (e *linePositionError) Unwrap() error {
+	return e.err
+}
+
+// Position returns the 0-based position of the error within its
+// line.
+func (e *linePositionError) Position() int {
+	return e.position
+}
+
+// readAll reads all available bytes from the given io.Reader into a
+// string, trimming any trailing newline characters, including the
+// "\r" of CRLF line endings. Only trailing characters are removed,
+// so positions in the result match positions in the original input.
+// At most maxBytes bytes of input, excluding trailing newlines, are
+// accepted; longer input is rejected with an *inputTooLongError
+// rather than silently truncated, since a truncated prefix may still
+// parse as a valid but wrong duration. If an error occurs during the
+// read operation, it returns an empty string and the error wrapped
+// with additional context.
 func readAll(rdr io.Reader, maxBytes int64) (string, error) {
-	limitRdr := io.LimitReader(rdr, maxBytes)
+	limitRdr := io.LimitReader(rdr, maxBytes+1)
 	inputBytes, err := io.ReadAll(limitRdr)
 	if err != nil {
 		return "", fmt.Errorf("error reading: %w", err)
 	}
-	return strings.TrimRight(string(inputBytes), "\n"), nil
+
+	input := strings.TrimRight(string(inputBytes), "\r\n")
+	if int64(len(input)) > maxBytes {
+		return "", &inputTooLongError{limit: maxBytes}
+	}
+
+	// If the limit was reached, the input is only complete if
+	// nothing but newlines follows.
+	if int64(len(inputBytes)) > maxBytes {
+		buf := make([]byte, 512)
+		for {
+			n, err := rdr.Read(buf)
+			for _, c := range buf[:n] {
+				if c != '\n' && c != '\r' {
+					return "", &inputTooLongError{limit: maxBytes}
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("error reading: %w", err)
+			}
+		}
+	}
+
+	return input, nil
+}
+
+// readLine reads the next line from br, removing the trailing "\n" or
+// "\r\n". It returns io.EOF once no input remains. A line whose
+// content exceeds maxBytes is consumed in full but not returned;
+// instead an *inputTooLongError is returned so that the caller can
+// report it and carry on with the next line.
+func readLine(br *bufio.Reader, maxBytes int64) (string, error) {
+	var line []byte
+	var n int64
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		n += int64(len(chunk))
+
+		// Keep at most the limit plus a line terminator.
+		if n <= maxBytes+2 {
+			line = append(line, chunk...)
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && n == 0 {
+			return "", io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("error reading: %w", err)
+		}
+		break
+	}
+
+	text := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if n > maxBytes+2 || int64(len(text)) > maxBytes {
+		return "", &inputTooLongError{limit: maxBytes}
+	}
+
+	return text, nil
 }
 
 // readInput determines the source of the input for parsing the
-// duration and retrieves the input. It first checks if there are any
-// elements in the remainingArgs slice. If so, the first element of
-// remainingArgs is returned. If remainingArgs is empty, the function
-// reads from the provided io.Reader.
+// durations and retrieves the input. It first checks if there are
+// any elements in the remainingArgs slice. If so, they are returned
+// unchanged, one duration per argument. If remainingArgs is empty,
+// the function reads a single duration from the provided io.Reader.
 //
 // Parameters:
 //
@@ -282,20 +869,183 @@ func readAll(rdr io.Reader, maxBytes int64) (string, error) {
 //
 // Returns:
 //
-//	value           The first element of remainingArgs or the string
-//	                read from the io.Reader.
+//	values          The elements of remainingArgs or the string read
+//	                from the io.Reader.
 //	err             An error if reading from the io.Reader fails.
-func readInput(rdr io.Reader, remainingArgs []string, maxBytes int64) (string, error) {
+func readInput(rdr io.Reader, remainingArgs []string, maxBytes int64) ([]string, error) {
 	if len(remainingArgs) > 0 {
-		return remainingArgs[0], nil
+		return remainingArgs, nil
+	}
+	input, err := readAll(rdr, maxBytes)
+	if err != nil {
+		return nil, err
 	}
-	return readAll(rdr, maxBytes)
+	return []string{input}, nil
+}
+
+// parseOptions controls how a duration string is parsed and
+// converted.
+type parseOptions struct {
+	// directive supplies the default unit and limits. A nil
+	// directive means defaultDirective.
+	directive *directive
+
+	// rounding selects how sub-millisecond values are reduced to
+	// whole milliseconds.
+	rounding roundingMode
+
+	// strict restricts the input to HAProxy's own time grammar:
+	// a single number with an optional unit.
+	strict bool
+
+	// plain restricts the input to whole numbers and unit
+	// symbols, as in "1m30s", without the fractions, unit names,
+	// ISO 8601 durations, clock times and expressions accepted
+	// otherwise, none of which HAProxy loads.
+	plain bool
+
+	// fix converts a value with misspelled units, such as
+	// "1w", as suggestUnits would correct it.
+	fix bool
+
+	// lenient accepts units in any order, and repeated units,
+	// summing them, as in "30m2h". It has no effect in strict
+	// mode.
+	lenient bool
+
+	// syntax selects the grammar of the input. Except with
+	// syntaxHAProxy, strict and lenient have no effect.
+	syntax inputSyntax
+}
+
+// parseDuration converts input into a time.Duration using the
+// composite duration syntax, or HAProxy's single-unit syntax if
+// opts.strict is set, interpreting values without a unit in the
+// default unit of the directive. A comptime.RangeError is returned if
+// the running total would exceed the directive's maximum, and a value
+// below its minimum is rejected with a minimumError. If input has
+// more than one problem they are all returned together as
+// parseErrors. Unless opts.strict or opts.plain is set, numbers may
+// have a fraction, digit separators or an exponent, as scanDecimal
+// allows, and ISO 8601 durations and clock times are accepted. With
+// opts.lenient, units may appear in any order and be repeated, as
+// parseAnyOrder allows. Other syntaxes are read as opts.syntax
+// selects.
+func parseDuration(input string, opts parseOptions) (time.Duration, error) {
+	d := opts.directive
+	if d == nil {
+		d = defaultDirective
+	}
+
+	if opts.syntax != syntaxHAProxy {
+		var duration time.Duration
+		var err error
+		switch opts.syntax {
+		case syntaxGo:
+			duration, err = parseGoDuration(input, d)
+		case syntaxSystemd:
+			var errs []error
+			duration, errs = scanSystemdTimespan(input, d)
+			err = joinErrors(errs)
+		}
+		if err == nil && duration < d.min {
+			return 0, &minimumError{directive: d}
+		}
+		return duration, err
+	}
+
+	parseMode := comptime.ParseModeMultiUnit
+	if opts.strict {
+		if input == "" {
+			return 0, &emptyValueError{}
+		}
+		parseMode = comptime.ParseModeSingleUnit
+	}
+
+	// comptime only accepts plain numbers and unit symbols, so
+	// values such as "1.5h", "1e3ms" or "2 hours 30 minutes" are
+	// parsed by scanDuration, which reports every problem itself.
+	// HAProxy accepts no more than comptime, so these are not
+	// accepted in strict or plain mode. ISO 8601 durations, such as
+	// "PT2H30M", and clock times, such as "01:30:00", have
+	// grammars of their own, as do arithmetic expressions, such
+	// as "2h - 15m".
+	extended := !opts.strict && !opts.plain && !isCompact(input)
+	ownGrammar := extended && (isExpression(input) || isISO8601(input) || isClock(input))
+
+	var duration time.Duration
+	var errs []error
+	var err error
+	switch {
+	case extended && isExpression(input):
+		duration, errs = scanExpression(input, d)
+		err = joinErrors(errs)
+	case extended && isISO8601(input):
+		duration, errs = scanISO8601(input, d)
+		err = joinErrors(errs)
+	case extended && isClock(input):
+		duration, errs = scanClock(input, d)
+		err = joinErrors(errs)
+	case extended:
+		duration, errs = scanDuration(input, d, parseMode, true)
+		err = joinErrors(errs)
+	default:
+		duration, err = comptime.ParseDuration(input, d.defaultUnit, parseMode, func(position int, value time.Duration, totalSoFar time.Duration) bool {
+			return value+totalSoFar <= d.max
+		})
+	}
+	if err != nil && opts.lenient && !opts.strict && !ownGrammar {
+		if total, ok, lenientErr := parseAnyOrder(input, d, err); ok {
+			if lenientErr != nil {
+				return 0, lenientErr
+			}
+			duration, err = total, nil
+		}
+	}
+	if err != nil {
+		if extended {
+			return 0, err
+		}
+		// Look for further problems so that they can all be
+		// fixed at once.
+		if errs := diagnose(input, d, parseMode); len(errs) > 1 {
+			errs[0] = err
+			return 0, parseErrors(errs)
+		}
+		return 0, err
+	}
+
+	if duration < d.min {
+		return 0, &minimumError{directive: d}
+	}
+
+	return duration, nil
+}
+
+// convertValue parses input as parseDuration does and rounds the
+// result to the resolution of the directive, usually whole
+// milliseconds, using opts.rounding. If precision was lost the
+// rounded value is returned together with a *precisionWarning, which
+// callers should report without treating it as a failure.
+func convertValue(input string, opts parseOptions) (time.Duration, error) {
+	duration, err := parseDuration(input, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	d := opts.directive
+	if d == nil {
+		d = defaultDirective
+	}
+
+	return roundDuration(input, duration, opts.rounding, d.resolution)
 }
 
 // convertDuration is the primary function for the haproxytime
-// tool. It parses command-line flags, reads input for a duration
-// string (either from arguments or stdin), converts it into a Go
-// time.Duration object, and then outputs the result.
+// tool. It parses command-line flags, reads input for one or more
+// duration strings (either from arguments or stdin), converts each
+// into a Go time.Duration object, and then outputs the results, one
+// per line, in the order given.
 //
 // Parameters:
 //   - stdin: the io.Reader from which input will be read.
@@ -306,74 +1056,308 @@ func readInput(rdr io.Reader, remainingArgs []string, maxBytes int64) (string, e
 //
 // Returns:
 //
-//   - 0 for successful execution, 1 for errors
+//   - exitSuccess for successful execution, otherwise the exit
+//     status of the first failure, such as exitUsage or exitSyntax
 //
 // Flags supported:
 //   - help: Show usage information
 //   - v: Show version information
 //   - h: Output duration in a human-readable format
+//   - o: Output format, one of ms, human, unit, iso8601, go, clock
+//     or json
 //   - m: Output the maximum HAProxy duration
+//   - q: Validate only, reporting the result in the exit status
+//   - batch: Convert each line read from stdin independently
+//   - continue: In batch mode, keep going after a line fails
+//   - max-bytes: Maximum number of bytes of stdin input per duration
+//   - strict: Accept only HAProxy's single-unit time syntax
+//   - fix: Convert values with misspelled units as corrected
+//   - lenient: Accept units in any order and repeated units
+//   - round: Rounding mode for values that are not a whole number
+//     of milliseconds
+//   - directive: Interpret the duration using the default unit and
+//     limits of the named HAProxy directive
+//   - syntax: Input syntax, one of haproxy, go or systemd
+//
+// If the first argument names a subcommand, such as "lint", the
+// remaining arguments are passed to that subcommand instead.
 //
 // If an error occurs, the function writes the error message to stderr
-// and returns 1. Otherwise, it writes the converted or maximum
-// duration to stdout and returns 0.
+// and returns the exit status for its class of failure. Every
+// argument is converted even if an earlier one fails, and the exit
+// status is that of the first failure. Otherwise, it writes the
+// converted or maximum durations to stdout and returns exitSuccess.
+// With -q nothing is written once the flags have been parsed.
 func convertDuration(rdr io.Reader, stdout, stderr io.Writer, args []string, exitHandler ExitHandler) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "lint":
+			return lintCommand(rdr, stdout, stderr, args[1:], exitHandler)
+		case "resolve":
+			return resolveCommand(rdr, stdout, stderr, args[1:], exitHandler)
+		case "rewrite":
+			return rewriteCommand(rdr, stdout, stderr, args[1:], exitHandler)
+		}
+	}
+
 	fs := flag.NewFlagSet("haproxytime", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	var showHelp, showVersion, printHuman, printMax bool
+	var showHelp, showVersion, printHuman, printMax, batch, keepGoing, quiet bool
+	var format outputFormat
+	var directiveName string
+	var opts parseOptions
+	var maxBytes int64
 
 	fs.BoolVar(&printHuman, "h", false, "Print duration value in a human-readable format")
+	fs.Var(&format, "o", "Output format: ms, human, unit, iso8601, go, clock or json")
 	fs.BoolVar(&printMax, "m", false, "Print the maximum HAProxy timeout value")
 	fs.BoolVar(&showHelp, "help", false, "Show usage information")
 	fs.BoolVar(&showVersion, "v", false, "Show version information")
+	fs.BoolVar(&quiet, "q", false, "Validate only: print nothing and report the result in the exit status")
+	fs.BoolVar(&batch, "batch", false, "Convert each line read from stdin independently")
+	fs.BoolVar(&keepGoing, "continue", false, "In batch mode, keep going after a line fails to convert")
+	fs.Int64Var(&maxBytes, "max-bytes", 256, "Maximum number of bytes of stdin input per duration")
+	fs.BoolVar(&opts.strict, "strict", false, "Accept only HAProxy's single-unit time syntax")
+	fs.BoolVar(&opts.fix, "fix", false, "Convert values with misspelled units as corrected")
+	fs.BoolVar(&opts.lenient, "lenient", false, "Accept units in any order and repeated units, summing them")
+	fs.Var(&opts.rounding, "round", "Rounding mode for sub-millisecond values: down, up, nearest or error")
+	fs.StringVar(&directiveName, "directive", "", "Interpret the duration as HAProxy does for the named directive")
+	fs.Var(&opts.syntax, "syntax", "Input syntax: haproxy, go or systemd")
 
 	if err := fs.Parse(args); err != nil {
 		safeFprintln(stderr, exitHandler, err)
-		return 1
+		return exitUsage
 	}
 
 	if showHelp {
 		safeFprintln(stderr, exitHandler, Usage)
-		return 1
+		return exitUsage
+	}
+
+	if printHuman {
+		format = formatHuman
 	}
 
 	if showVersion {
 		safeFprintf(stderr, exitHandler, "haproxytime %s\n", version())
-		return 0
+		return exitSuccess
+	}
+
+	if maxBytes < 1 {
+		safeFprintln(stderr, exitHandler, "-max-bytes must be at least 1")
+		return exitUsage
+	}
+
+	if opts.strict && opts.syntax != syntaxHAProxy {
+		safeFprintf(stderr, exitHandler, "-strict cannot be used with -syntax %s\n", opts.syntax.String())
+		return exitUsage
+	}
+
+	opts.directive = defaultDirective
+	if directiveName != "" {
+		var err error
+		if opts.directive, err = lookupDirective(directiveName); err != nil {
+			safeFprintln(stderr, exitHandler, err)
+			return exitUsage
+		}
+	}
+
+	if batch && len(fs.Args()) > 0 {
+		safeFprintln(stderr, exitHandler, "-batch reads durations from stdin and accepts no arguments")
+		return exitUsage
+	}
+
+	// Only the exit status matters when validating.
+	if quiet {
+		stdout, stderr = io.Discard, io.Discard
 	}
 
 	if printMax {
-		output(stdout, exitHandler, maxTimeout, printHuman)
-		return 0
+		output(stdout, exitHandler, opts.directive.max, format)
+		return exitSuccess
+	}
+
+	if batch {
+		return convertLines(rdr, stdout, stderr, exitHandler, opts, format, keepGoing, maxBytes)
 	}
 
-	input, err := readInput(rdr, fs.Args(), 256)
+	inputs, err := readInput(rdr, fs.Args(), maxBytes)
 	if err != nil {
-		safeFprintln(stderr, exitHandler, err)
-		return 1
+		var tooLong *inputTooLongError
+		if errors.As(err, &tooLong) && format == formatJSON {
+			writeJSON(stdout, exitHandler, newJSONResult("", 0, err, parseOptions{}))
+		} else {
+			safeFprintln(stderr, exitHandler, err)
+		}
+		return exitRead
 	}
 
-	duration, err := comptime.ParseDuration(input, comptime.Millisecond, comptime.ParseModeMultiUnit, func(position int, value time.Duration, totalSoFar time.Duration) bool {
-		return value+totalSoFar <= maxTimeout
-	})
+	status := exitSuccess
+	for _, input := range inputs {
+		report := func(err error) {
+			if len(fs.Args()) > 0 {
+				// If there are command-line arguments,
+				// print positional error.
+				printPositionalError(stderr, exitHandler, err, input)
+			} else {
+				// Otherwise the input came from stdin
+				// and may span several lines.
+				printStdinError(stderr, exitHandler, err, input)
+			}
+		}
 
-	if err != nil {
-		// If there are command-line arguments, print
-		// positional error.
-		if len(fs.Args()) > 0 {
-			printPositionalError(stderr, exitHandler, err, fs.Args()[0])
-			return 1
+		if code := convertOne(stdout, stderr, exitHandler, input, report, opts, format); status == exitSuccess {
+			status = code
 		}
+	}
 
-		// If there are no command-line arguments, simply
-		// print the error.
-		safeFprintln(stderr, exitHandler, err)
-		return 1
+	return status
+}
+
+// errorReporter writes a diagnostic for err, which was produced while
+// converting a duration, in a form suited to where the input came
+// from.
+type errorReporter func(err error)
+
+// convertOne converts a single duration string and writes the result
+// to stdout in the given format. Warnings are written to stderr and
+// errors are passed to report, except with formatJSON where the
+// result, warning or error is written to stdout as a single JSON
+// object. A value rejected for its syntax is followed by a suggested
+// correction if suggestUnits finds one, and with opts.fix the
+// correction is converted instead. A value with units out of order is
+// accepted with a warning showing its canonical form in lenient mode,
+// and rejected with the canonical form shown otherwise. It returns
+// exitSuccess, or the exit status for the failure, as given by
+// exitStatus.
+func convertOne(stdout, stderr io.Writer, exitHandler ExitHandler, input string, report errorReporter, opts parseOptions, format outputFormat) int {
+	duration, err := convertValue(input, opts)
+
+	var suggestion string
+	if err != nil && exitStatus(err) == exitSyntax {
+		suggestion = suggestUnits(input, opts)
+	}
+
+	fixed := opts.fix && suggestion != ""
+	if fixed {
+		duration, err = convertValue(suggestion, opts)
+	}
+
+	var warning *precisionWarning
+	failed := err != nil && !errors.As(err, &warning)
+
+	// The canonical form of a value whose units are out of
+	// order: accepted with a warning in lenient mode, otherwise
+	// shown alongside the error.
+	var reordered string
+	if opts.lenient && !failed || !opts.lenient && isUnitOrderError(err) {
+		reordered = reorderedForm(input, opts)
+	}
+
+	if format == formatJSON {
+		result := newJSONResult(input, duration, err, opts)
+		result.Suggestion = suggestion
+		result.Fixed = fixed
+		result.Reordered = reordered
+		writeJSON(stdout, exitHandler, result)
+		if result.Error != nil {
+			return exitStatus(err)
+		}
+		return exitSuccess
+	}
+
+	if fixed {
+		safeFprintf(stderr, exitHandler, "warning: %s is not valid; converted %s instead\n", input, suggestion)
+	}
+	if reordered != "" && !failed {
+		safeFprintf(stderr, exitHandler, "warning: %s has units out of order; read as %s\n", input, reordered)
+	}
+
+	if warning != nil {
+		safeFprintln(stderr, exitHandler, warning)
+	}
+
+	if failed {
+		report(err)
+
+		if opts.strict {
+			if msg := haproxyMessage(input, opts.directive, err); msg != "" {
+				safeFprintf(stderr, exitHandler, "HAProxy would report: %s\n", msg)
+			}
+		}
+		if suggestion != "" {
+			safeFprintf(stderr, exitHandler, "did you mean %s?\n", suggestion)
+		}
+		if reordered != "" {
+			safeFprintf(stderr, exitHandler, "warning: units are out of order; -lenient reads this as %s\n", reordered)
+		}
+		return exitStatus(err)
+	}
+
+	output(stdout, exitHandler, duration, format)
+	return exitSuccess
+}
+
+// convertLines reads durations from rdr, one per line, converting and
+// writing each as soon as it is read so that arbitrarily long streams
+// can be processed. Trailing "\r" characters are ignored. An error is
+// reported as "<stdin>:line:column: error", followed by the line and a
+// caret under the offending character. Blank lines are rejected as
+// empty values. A line longer than maxBytes is reported as an error
+// rather than truncated. Conversion stops at the first line that
+// fails unless keepGoing is true. It returns exitSuccess if every
+// line was converted, otherwise the exit status of the first failure.
+func convertLines(rdr io.Reader, stdout, stderr io.Writer, exitHandler ExitHandler, opts parseOptions, format outputFormat, keepGoing bool, maxBytes int64) int {
+	br := bufio.NewReader(rdr)
+	status := exitSuccess
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := readLine(br, maxBytes)
+		if err == io.EOF {
+			break
+		}
+
+		report := func(err error) {
+			printLineError(stderr, exitHandler, err, lineNumber, line)
+		}
+
+		code := exitSuccess
+		var tooLong *inputTooLongError
+		switch {
+		case errors.As(err, &tooLong) && format == formatJSON:
+			writeJSON(stdout, exitHandler, newJSONResult(line, 0, err, parseOptions{}))
+			code = exitRead
+		case errors.As(err, &tooLong):
+			report(err)
+			code = exitRead
+		case err != nil:
+			safeFprintln(stderr, exitHandler, err)
+			return exitRead
+		case line == "" && format == formatJSON:
+			writeJSON(stdout, exitHandler, newJSONResult(line, 0, &emptyValueError{}, opts))
+			code = exitSyntax
+		case line == "":
+			// A blank line would otherwise convert to 0ms,
+			// which HAProxy treats as no timeout.
+			report(&emptyValueError{})
+			code = exitSyntax
+		default:
+			code = convertOne(stdout, stderr, exitHandler, line, report, opts, format)
+		}
+
+		if code == exitSuccess {
+			continue
+		}
+		if status == exitSuccess {
+			status = code
+		}
+		if !keepGoing {
+			break
+		}
 	}
 
-	output(stdout, exitHandler, duration, printHuman)
-	return 0
+	return status
 }
 
 func main() {
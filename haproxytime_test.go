@@ -113,63 +113,1022 @@ func TestConvertDuration(t *testing.T) {
 	}, {
 		description:    "help flag",
 		args:           []string{"-help"},
-		expectedExit:   1,
+		expectedExit:   2,
 		expectedStdout: "",
 		expectedStderr: cmd.Usage,
 	}, {
 		description:    "single invalid flag",
 		args:           []string{"-z"},
-		expectedExit:   1,
+		expectedExit:   2,
 		expectedStdout: "",
 		expectedStderr: "flag provided but not defined: -z",
 	}, {
 		description:    "mix of valid and invalid flags",
 		args:           []string{"-h", "-z", "100ms"},
-		expectedExit:   1,
+		expectedExit:   2,
 		expectedStdout: "",
 		expectedStderr: "flag provided but not defined: -z",
 	}, {
 		description:    "syntax error reporting from args",
 		args:           []string{"24d20h31m23s647msO000us"},
-		expectedExit:   1,
+		expectedExit:   4,
 		expectedStdout: "",
 		expectedStderr: "syntax error at position 18: invalid number\n24d20h31m23s647msO000us\n                 ^",
 	}, {
 		description:    "syntax error reporting from stdin",
 		stdin:          strings.NewReader("24d20h31m23s647msO000us\n"),
-		expectedExit:   1,
+		expectedExit:   4,
 		expectedStdout: "",
-		expectedStderr: "syntax error at position 18: invalid number",
+		expectedStderr: "syntax error at position 18: invalid number\n24d20h31m23s647msO000us\n                 ^",
 	}, {
 		description:    "value exceeds HAProxy's maximum duration from args",
 		args:           []string{"24d20h31m23s647ms1000us"},
-		expectedExit:   1,
+		expectedExit:   6,
 		expectedStdout: "",
-		expectedStderr: "range error at position 18\n24d20h31m23s647ms1000us\n                 ^",
+		expectedStderr: "range error at position 18\n24d20h31m23s647ms1000us\n                 ^~~~~~",
 	}, {
 		description:    "value exceeds HAProxy's maximum description from stdin",
 		stdin:          strings.NewReader("24d20h31m23s647ms1000us\n"),
-		expectedExit:   1,
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 18\n24d20h31m23s647ms1000us\n                 ^~~~~~",
+	}, {
+		description:    "syntax error reporting from stdin with CRLF line ending",
+		stdin:          strings.NewReader("2h3x\r\n"),
+		expectedExit:   4,
 		expectedStdout: "",
-		expectedStderr: "range error at position 18",
+		expectedStderr: "syntax error at position 4: invalid unit\n2h3x\n   ^",
+	}, {
+		description:    "syntax error reporting from multi-line stdin",
+		stdin:          strings.NewReader("2h\r\n3x\n"),
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "<stdin>:1:3: syntax error at position 3: invalid number\n2h\n  ^\n<stdin>:2:2: syntax error at position 2: invalid unit\n3x\n ^",
+	}, {
+		description:    "batch conversion error reporting with CRLF line endings",
+		args:           []string{"-batch"},
+		stdin:          strings.NewReader("1s\r\n2h3x\r\n"),
+		expectedExit:   4,
+		expectedStdout: "1000ms",
+		expectedStderr: "<stdin>:2:4: syntax error at position 4: invalid unit\n2h3x\n   ^",
 	}, {
 		description:    "simulated reading failure",
 		stdin:          &errorReader{},
-		expectedExit:   1,
+		expectedExit:   3,
 		expectedStdout: "",
 		expectedStderr: "error reading: simulated read error",
 	}, {
 		description:    "overflow error from args",
 		args:           []string{"9223372036855ms"},
-		expectedExit:   1,
+		expectedExit:   5,
 		expectedStdout: "",
-		expectedStderr: "overflow error at position 1\n9223372036855ms\n^",
+		expectedStderr: "overflow error at position 1\n9223372036855ms\n^~~~~~~~~~~~~~~",
 	}, {
 		description:    "overflow error from stdin",
 		stdin:          strings.NewReader("9223372036855ms"),
-		expectedExit:   1,
+		expectedExit:   5,
+		expectedStdout: "",
+		expectedStderr: "overflow error at position 1\n9223372036855ms\n^~~~~~~~~~~~~~~",
+	}, {
+		description:    "directive with a default unit of seconds",
+		args:           []string{"-directive", "tune.ssl.lifetime", "300"},
+		expectedExit:   0,
+		expectedStdout: "300000ms",
+		expectedStderr: "",
+	}, {
+		description:    "directive stored in seconds is rounded to whole seconds",
+		args:           []string{"-directive", "stats refresh", "2.5", "1500ms"},
+		expectedExit:   0,
+		expectedStdout: "2000ms\n1000ms",
+		expectedStderr: "warning: 2.5 is not a whole number of seconds; rounded down to 2000ms\nwarning: 1500ms is not a whole number of seconds; rounded down to 1000ms",
+	}, {
+		description:    "directive stored in seconds rejects values below a second",
+		args:           []string{"-strict", "-directive", "tune.ssl.lifetime", "500ms"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "underflow error: 500ms rounds to 0s, which HAProxy treats as no timeout\n500ms\n^~~~~\nHAProxy would report: timer underflow in argument '500ms' to 'tune.ssl.lifetime' (minimum non-null value is 1 s)",
+	}, {
+		description:    "directive stored in seconds with -round error",
+		args:           []string{"-round", "error", "-directive", "tune.ssl.lifetime", "1500ms"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "precision error: 1500ms is not a whole number of seconds\n1500ms\n^~~~~~",
+	}, {
+		description:    "directive with explicit units",
+		args:           []string{"-directive", "inter", "-h", "2s"},
+		expectedExit:   0,
+		expectedStdout: "2s",
+		expectedStderr: "",
+	}, {
+		description:    "directive below its minimum",
+		args:           []string{"-directive", "inter", "0"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error: inter must be at least 1ms\n0\n^",
+	}, {
+		description:    "directive above its maximum",
+		args:           []string{"-directive", "tune.idletimer", "2m"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 1\n2m\n^~",
+	}, {
+		description:    "maximum value of a directive",
+		args:           []string{"-m", "-directive", "tune.idletimer"},
+		expectedExit:   0,
+		expectedStdout: "65535ms",
+		expectedStderr: "",
+	}, {
+		description:    "unknown directive",
+		args:           []string{"-directive", "timeout bogus", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "unknown directive \"timeout bogus\"",
+	}, {
+		description:    "sub-millisecond value rounded down by default",
+		args:           []string{"1500us"},
+		expectedExit:   0,
+		expectedStdout: "1ms",
+		expectedStderr: "warning: 1500us is not a whole number of milliseconds; rounded down to 1ms",
+	}, {
+		description:    "sub-millisecond value rounded up",
+		args:           []string{"-round", "up", "-h", "1s1us"},
+		expectedExit:   0,
+		expectedStdout: "1s1ms",
+		expectedStderr: "warning: 1s1us is not a whole number of milliseconds; rounded up to 1001ms",
+	}, {
+		description:    "sub-millisecond value rounded to nearest",
+		args:           []string{"-round", "nearest", "1499us"},
+		expectedExit:   0,
+		expectedStdout: "1ms",
+		expectedStderr: "warning: 1499us is not a whole number of milliseconds; rounded down to 1ms",
+	}, {
+		description:    "halfway value rounded to nearest",
+		args:           []string{"-round", "nearest", "1500us"},
+		expectedExit:   0,
+		expectedStdout: "2ms",
+		expectedStderr: "warning: 1500us is not a whole number of milliseconds; rounded up to 2ms",
+	}, {
+		description:    "sub-millisecond value rejected",
+		args:           []string{"-round", "error", "1500us"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "precision error: 1500us is not a whole number of milliseconds\n1500us\n^~~~~~",
+	}, {
+		description:    "whole milliseconds are exact in every rounding mode",
+		args:           []string{"-round", "error", "2000us"},
+		expectedExit:   0,
+		expectedStdout: "2ms",
+		expectedStderr: "",
+	}, {
+		description:    "non-zero value that rounds to zero",
+		args:           []string{"500us"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "underflow error: 500us rounds to 0ms, which HAProxy treats as no timeout\n500us\n^~~~~",
+	}, {
+		description:    "non-zero value that rounds to zero from stdin",
+		stdin:          strings.NewReader("400us\n"),
+		args:           []string{"-round", "nearest"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "underflow error: 400us rounds to 0ms, which HAProxy treats as no timeout\n400us\n^~~~~",
+	}, {
+		description:    "invalid rounding mode",
+		args:           []string{"-round", "sideways", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "invalid value \"sideways\" for flag -round: must be one of down, error, nearest, up",
+	}, {
+		description:    "strict mode accepts a single unit",
+		args:           []string{"-strict", "90s"},
+		expectedExit:   0,
+		expectedStdout: "90000ms",
+		expectedStderr: "",
+	}, {
+		description:    "strict mode rejects composite values",
+		args:           []string{"-strict", "1m30s"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: unexpected characters in single unit mode\n1m30s\n  ^~~\nHAProxy would report: unexpected character '3' in 'timeout'",
+	}, {
+		description:    "strict mode reports a multi-byte character whole",
+		args:           []string{"-strict", "1µs"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\n1µs\n ^~\nHAProxy would report: unexpected character 'µ' in 'timeout'",
+	}, {
+		description:    "strict mode reports HAProxy's overflow message",
+		args:           []string{"-strict", "-directive", "timeout client", "25d"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 1\n25d\n^~~\nHAProxy would report: timer overflow in argument '25d' to 'timeout client' (maximum value is 2147483647 ms)",
+	}, {
+		description:    "strict mode reports HAProxy's underflow message",
+		args:           []string{"-strict", "500us"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "underflow error: 500us rounds to 0ms, which HAProxy treats as no timeout\n500us\n^~~~~\nHAProxy would report: timer underflow in argument '500us' to 'timeout' (minimum non-null value is 1 ms)",
+	}, {
+		description:    "strict mode rejects an empty value",
+		args:           []string{"-strict"},
+		stdin:          &emptyStringReader{},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 1: empty value\n\n^\nHAProxy would report: 'timeout' expects an integer value",
+	}, {
+		description:    "single unit output in seconds",
+		args:           []string{"-o", "unit", "90000"},
+		expectedExit:   0,
+		expectedStdout: "90s",
+		expectedStderr: "",
+	}, {
+		description:    "single unit output in days",
+		args:           []string{"-o", "unit", "86400000ms"},
+		expectedExit:   0,
+		expectedStdout: "1d",
+		expectedStderr: "",
+	}, {
+		description:    "single unit output in minutes",
+		args:           []string{"-o", "unit", "1h30m"},
+		expectedExit:   0,
+		expectedStdout: "90m",
+		expectedStderr: "",
+	}, {
+		description:    "single unit output that needs milliseconds",
+		args:           []string{"-o", "unit", "1500"},
+		expectedExit:   0,
+		expectedStdout: "1500ms",
+		expectedStderr: "",
+	}, {
+		description:    "single unit output of zero",
+		args:           []string{"-o", "unit", "0s"},
+		expectedExit:   0,
+		expectedStdout: "0ms",
+		expectedStderr: "",
+	}, {
+		description:    "human output format",
+		args:           []string{"-o", "human", "90000"},
+		expectedExit:   0,
+		expectedStdout: "1m30s",
+		expectedStderr: "",
+	}, {
+		description:    "invalid output format",
+		args:           []string{"-o", "yaml", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "invalid value \"yaml\" for flag -o: must be one of clock, go, human, iso8601, json, ms, unit",
+	}, {
+		description:    "every argument is converted",
+		args:           []string{"30s", "5m", "1h"},
+		expectedExit:   0,
+		expectedStdout: "30000ms\n300000ms\n3600000ms",
+		expectedStderr: "",
+	}, {
+		description:    "every argument is converted in human-readable format",
+		args:           []string{"-h", "90000", "86400000"},
+		expectedExit:   0,
+		expectedStdout: "1m30s\n1d",
+		expectedStderr: "",
+	}, {
+		description:    "failures do not stop later arguments",
+		args:           []string{"30s", "2h3x", "1h", "30d"},
+		expectedExit:   4,
+		expectedStdout: "30000ms\n3600000ms",
+		expectedStderr: "syntax error at position 4: invalid unit\n2h3x\n   ^\nrange error at position 1\n30d\n^~~",
+	}, {
+		description:    "batch conversion of each line",
+		args:           []string{"-batch"},
+		stdin:          strings.NewReader("30s\n5m\r\n1h\n"),
+		expectedExit:   0,
+		expectedStdout: "30000ms\n300000ms\n3600000ms",
+		expectedStderr: "",
+	}, {
+		description:    "batch conversion stops at the first bad line",
+		args:           []string{"-batch"},
+		stdin:          strings.NewReader("30s\n2h3x\n1h\n"),
+		expectedExit:   4,
+		expectedStdout: "30000ms",
+		expectedStderr: "<stdin>:2:4: syntax error at position 4: invalid unit\n2h3x\n   ^",
+	}, {
+		description:    "batch conversion continues past bad lines",
+		args:           []string{"-batch", "-continue", "-h"},
+		stdin:          strings.NewReader("30s\n2h3x\n90000\n30d"),
+		expectedExit:   4,
+		expectedStdout: "30s\n1m30s",
+		expectedStderr: "<stdin>:2:4: syntax error at position 4: invalid unit\n2h3x\n   ^\n<stdin>:4:1: range error at position 1\n30d\n^~~",
+	}, {
+		description:    "batch conversion rejects blank lines",
+		args:           []string{"-batch", "-continue"},
+		stdin:          strings.NewReader("30s\n\n1h\n"),
+		expectedExit:   4,
+		expectedStdout: "30000ms\n3600000ms",
+		expectedStderr: "<stdin>:2:1: syntax error at position 1: empty value\n\n^",
+	}, {
+		description:    "batch conversion does not accept arguments",
+		args:           []string{"-batch", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "-batch reads durations from stdin and accepts no arguments",
+	}, {
+		description:    "batch conversion read failure",
+		args:           []string{"-batch"},
+		stdin:          &errorReader{},
+		expectedExit:   3,
+		expectedStdout: "",
+		expectedStderr: "error reading: simulated read error",
+	}, {
+		description:    "stdin input at the byte limit",
+		stdin:          strings.NewReader(strings.Repeat("0", 254) + "1s\n"),
+		expectedExit:   0,
+		expectedStdout: "1000ms",
+		expectedStderr: "",
+	}, {
+		description:    "stdin input over the byte limit is not truncated",
+		stdin:          strings.NewReader(strings.Repeat("0", 255) + "1s\n"),
+		expectedExit:   3,
+		expectedStdout: "",
+		expectedStderr: "input exceeds the 256 byte limit set by -max-bytes",
+	}, {
+		description:    "stdin input with a configured byte limit",
+		args:           []string{"-max-bytes", "4"},
+		stdin:          strings.NewReader("1000s"),
+		expectedExit:   3,
+		expectedStdout: "",
+		expectedStderr: "input exceeds the 4 byte limit set by -max-bytes",
+	}, {
+		description:    "trailing newlines do not count towards the byte limit",
+		args:           []string{"-max-bytes", "2"},
+		stdin:          strings.NewReader("1s\n\n\n\n\n\n"),
+		expectedExit:   0,
+		expectedStdout: "1000ms",
+		expectedStderr: "",
+	}, {
+		description:    "content after trailing newlines exceeds the byte limit",
+		args:           []string{"-max-bytes", "2"},
+		stdin:          strings.NewReader("1s\n\n\n\n\n\n1"),
+		expectedExit:   3,
+		expectedStdout: "",
+		expectedStderr: "input exceeds the 2 byte limit set by -max-bytes",
+	}, {
+		description:    "batch lines over the byte limit",
+		args:           []string{"-batch", "-continue", "-max-bytes", "3"},
+		stdin:          strings.NewReader("1s\n12345s\n2s\r\n"),
+		expectedExit:   3,
+		expectedStdout: "1000ms\n2000ms",
+		expectedStderr: "<stdin>:2: input exceeds the 3 byte limit set by -max-bytes",
+	}, {
+		description:    "batch lines longer than the read buffer",
+		args:           []string{"-batch", "-max-bytes", "10000"},
+		stdin:          strings.NewReader(strings.Repeat("0", 5000) + "1s\n2s"),
+		expectedExit:   0,
+		expectedStdout: "1000ms\n2000ms",
+		expectedStderr: "",
+	}, {
+		description:    "invalid byte limit",
+		args:           []string{"-max-bytes", "0", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "-max-bytes must be at least 1",
+	}, {
+		description:    "json output",
+		args:           []string{"-o", "json", "1d1m30s"},
+		expectedExit:   0,
+		expectedStdout: `{"input":"1d1m30s","milliseconds":86490000,"human":"1d1m30s","units":{"days":1,"hours":0,"minutes":1,"seconds":30,"milliseconds":0}}`,
+		expectedStderr: "",
+	}, {
+		description:    "json output of a syntax error",
+		args:           []string{"-o", "json", "1s", "2h3x"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"1s","milliseconds":1000,"human":"1s","units":{"days":0,"hours":0,"minutes":0,"seconds":1,"milliseconds":0}}` + "\n" + `{"input":"2h3x","error":{"class":"SyntaxError","cause":"InvalidUnit","position":3,"message":"syntax error at position 4: invalid unit"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "json output of range and overflow errors",
+		args:           []string{"-o", "json", "30d", "9223372036855ms"},
+		expectedExit:   6,
+		expectedStdout: `{"input":"30d","error":{"class":"RangeError","position":0,"message":"range error at position 1"}}` + "\n" + `{"input":"9223372036855ms","error":{"class":"OverflowError","position":0,"message":"overflow error at position 1"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "json output includes warnings and HAProxy's message in strict mode",
+		args:           []string{"-o", "json", "-strict", "1500us", "1m30s"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"1500us","milliseconds":1,"human":"1ms","units":{"days":0,"hours":0,"minutes":0,"seconds":0,"milliseconds":1},"warning":"warning: 1500us is not a whole number of milliseconds; rounded down to 1ms"}` + "\n" + `{"input":"1m30s","error":{"class":"SyntaxError","cause":"UnexpectedCharactersInSingleUnitMode","position":2,"message":"syntax error at position 3: unexpected characters in single unit mode","haproxy":"unexpected character '3' in 'timeout'"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "json output of a directive's minimum",
+		args:           []string{"-o", "json", "-directive", "inter", "0"},
+		expectedExit:   6,
+		expectedStdout: `{"input":"0","error":{"class":"MinimumError","position":0,"message":"range error: inter must be at least 1ms"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "json output in batch mode",
+		args:           []string{"-o", "json", "-batch", "-continue", "-max-bytes", "3"},
+		stdin:          strings.NewReader("1s\n12345s\nx\n"),
+		expectedExit:   3,
+		expectedStdout: `{"input":"1s","milliseconds":1000,"human":"1s","units":{"days":0,"hours":0,"minutes":0,"seconds":1,"milliseconds":0}}` + "\n" + `{"input":"","error":{"class":"InputTooLongError","message":"input exceeds the 3 byte limit set by -max-bytes"}}` + "\n" + `{"input":"x","error":{"class":"SyntaxError","cause":"InvalidNumber","position":0,"message":"syntax error at position 1: invalid number"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "json output of the maximum",
+		args:           []string{"-o", "json", "-m"},
+		expectedExit:   0,
+		expectedStdout: `{"input":"","milliseconds":2147483647,"human":"24d20h31m23s647ms","units":{"days":24,"hours":20,"minutes":31,"seconds":23,"milliseconds":647}}`,
+		expectedStderr: "",
+	}, {
+		description:    "every error in a value is reported",
+		args:           []string{"1x2h3y"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\nsyntax error at position 6: invalid unit\n1x2h3y\n ^   ^",
+	}, {
+		description:    "every unit out of order is reported",
+		args:           []string{"1s2h3m"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 4: invalid unit order\nsyntax error at position 6: invalid unit order\n1s2h3m\n   ^ ^\nwarning: units are out of order; -lenient reads this as 2h3m1s",
+	}, {
+		description:    "range and syntax errors are reported together",
+		args:           []string{"30d5x"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 1\nsyntax error at position 5: invalid unit\n30d5x\n^~~ ^",
+	}, {
+		description:    "overflow and syntax errors are reported together",
+		args:           []string{"99999999999999999999s2x"},
+		expectedExit:   5,
+		expectedStdout: "",
+		expectedStderr: "overflow error at position 1\nsyntax error at position 23: invalid unit\n99999999999999999999s2x\n^~~~~~~~~~~~~~~~~~~~~ ^",
+	}, {
+		description:    "every error in a batch line is reported",
+		args:           []string{"-batch"},
+		stdin:          strings.NewReader("1s\n1x2h3y\n"),
+		expectedExit:   4,
+		expectedStdout: "1000ms",
+		expectedStderr: "<stdin>:2:2: syntax error at position 2: invalid unit\n<stdin>:2:6: syntax error at position 6: invalid unit\n1x2h3y\n ^   ^",
+	}, {
+		description:    "strict mode reports the first error to HAProxy",
+		args:           []string{"-strict", "1x2"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\nsyntax error at position 3: unexpected characters in single unit mode\n1x2\n ^^\nHAProxy would report: unexpected character 'x' in 'timeout'",
+	}, {
+		description:    "json output lists every error",
+		args:           []string{"-o", "json", "1x2h3y"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"1x2h3y","error":{"class":"SyntaxError","cause":"InvalidUnit","position":1,"message":"syntax error at position 2: invalid unit"},"errors":[{"class":"SyntaxError","cause":"InvalidUnit","position":1,"message":"syntax error at position 2: invalid unit"},{"class":"SyntaxError","cause":"InvalidUnit","position":5,"message":"syntax error at position 6: invalid unit"}]}`,
+		expectedStderr: "",
+	}, {
+		description:    "misspelled units are corrected in a suggestion",
+		args:           []string{"1w12hrs"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\n1w12hrs\n ^\ndid you mean 7d12h?",
+	}, {
+		description:    "weeks are suggested as days",
+		args:           []string{"1w2d"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\n1w2d\n ^\ndid you mean 9d?",
+	}, {
+		description:    "micro sign and nanoseconds are suggested as microseconds",
+		args:           []string{"5000µs", "2000000ns"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 5: invalid unit\n5000µs\n    ^~\ndid you mean 5000us?\nsyntax error at position 8: invalid unit\n2000000ns\n       ^~\ndid you mean 2000us?",
+	}, {
+		description:    "no suggestion for an unknown unit",
+		args:           []string{"2x"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\n2x\n ^",
+	}, {
+		description:    "no suggestion that would itself be rejected",
+		args:           []string{"-strict", "2hrs30mins"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: unexpected characters in single unit mode\n2hrs30mins\n  ^~\nHAProxy would report: unexpected character 'r' in 'timeout'",
+	}, {
+		description:    "fix converts the suggested correction",
+		args:           []string{"-fix", "1w2d", "2000000ns", "2x"},
+		expectedExit:   4,
+		expectedStdout: "777600000ms\n2ms",
+		expectedStderr: "warning: 1w2d is not valid; converted 9d instead\nwarning: 2000000ns is not valid; converted 2000us instead\nsyntax error at position 2: invalid unit\n2x\n ^",
+	}, {
+		description:    "json output includes the suggestion",
+		args:           []string{"-o", "json", "2w"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"2w","error":{"class":"SyntaxError","cause":"InvalidUnit","position":1,"message":"syntax error at position 2: invalid unit"},"suggestion":"14d"}`,
+		expectedStderr: "",
+	}, {
+		description:    "json output of a fixed value",
+		args:           []string{"-o", "json", "-fix", "2w"},
+		expectedExit:   0,
+		expectedStdout: `{"input":"2w","milliseconds":1209600000,"human":"14d","units":{"days":14,"hours":0,"minutes":0,"seconds":0,"milliseconds":0},"suggestion":"14d","fixed":true}`,
+		expectedStderr: "",
+	}, {
+		description:    "lenient mode accepts units in any order",
+		args:           []string{"-lenient", "30m2h", "2h30m"},
+		expectedExit:   0,
+		expectedStdout: "9000000ms\n9000000ms",
+		expectedStderr: "warning: 30m2h has units out of order; read as 2h30m",
+	}, {
+		description:    "lenient mode sums repeated units",
+		args:           []string{"-lenient", "1h1h"},
+		expectedExit:   0,
+		expectedStdout: "7200000ms",
+		expectedStderr: "warning: 1h1h has units out of order; read as 2h",
+	}, {
+		description:    "lenient mode keeps microseconds in the canonical form",
+		args:           []string{"-lenient", "-round", "up", "500us1ms"},
+		expectedExit:   0,
+		expectedStdout: "2ms",
+		expectedStderr: "warning: 500us1ms has units out of order; read as 1ms500us\nwarning: 500us1ms is not a whole number of milliseconds; rounded up to 2ms",
+	}, {
+		description:    "lenient mode checks the range of the total",
+		args:           []string{"-lenient", "1s30d"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 3\n1s30d\n  ^~~",
+	}, {
+		description:    "lenient mode still rejects other errors",
+		args:           []string{"-lenient", "30m2h3x"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 5: invalid unit order\nsyntax error at position 7: invalid unit\n30m2h3x\n    ^ ^",
+	}, {
+		description:    "units out of order show the canonical form",
+		args:           []string{"1h1h"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 4: invalid unit order\n1h1h\n   ^\nwarning: units are out of order; -lenient reads this as 2h",
+	}, {
+		description:    "json output of a reordered value",
+		args:           []string{"-o", "json", "-lenient", "30m2h"},
+		expectedExit:   0,
+		expectedStdout: `{"input":"30m2h","milliseconds":9000000,"human":"2h30m","units":{"days":0,"hours":2,"minutes":30,"seconds":0,"milliseconds":0},"reordered":"2h30m"}`,
+		expectedStderr: "",
+	}, {
+		description:    "fractional values",
+		args:           []string{"1.5h", ".25s", "0.5s", "1.5d2.5h", "1.000s"},
+		expectedExit:   0,
+		expectedStdout: "5400000ms\n250ms\n500ms\n138600000ms\n1000ms",
+		expectedStderr: "",
+	}, {
+		description:    "fraction of a millisecond is rounded",
+		args:           []string{"-round", "nearest", "1.5ms"},
+		expectedExit:   0,
+		expectedStdout: "2ms",
+		expectedStderr: "warning: 1.5ms is not a whole number of milliseconds; rounded up to 2ms",
+	}, {
+		description:    "fraction of a microsecond",
+		args:           []string{"1.0000001s"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 1: fraction is not a whole number of microseconds\n1.0000001s\n^~~~~~~~~~",
+	}, {
+		description:    "malformed fractions",
+		args:           []string{"1..5s", "1.s", "."},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: invalid number\n1..5s\n  ^\nsyntax error at position 3: invalid number\n1.s\n  ^\nsyntax error at position 1: invalid number\n.\n^",
+	}, {
+		description:    "every problem in a fractional value is reported",
+		args:           []string{"1.5x2..5s"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 4: invalid unit\nsyntax error at position 7: invalid number\n1.5x2..5s\n   ^  ^",
+	}, {
+		description:    "fractional value out of range",
+		args:           []string{"24.9d"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 1\n24.9d\n^~~~~",
+	}, {
+		description:    "fractions are rejected in strict mode",
+		args:           []string{"-strict", "1.5s"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\nsyntax error at position 3: unexpected characters in single unit mode\n1.5s\n ^^~\nHAProxy would report: unexpected character '.' in 'timeout'",
+	}, {
+		description:    "lenient mode accepts fractions in any order",
+		args:           []string{"-lenient", "30m1.5h"},
+		expectedExit:   0,
+		expectedStdout: "7200000ms",
+		expectedStderr: "warning: 30m1.5h has units out of order; read as 2h",
+	}, {
+		description:    "json output of an inexact fraction",
+		args:           []string{"-o", "json", "1.0000001s"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"1.0000001s","error":{"class":"SyntaxError","cause":"InexactFraction","position":0,"message":"syntax error at position 1: fraction is not a whole number of microseconds"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "digit separators",
+		args:           []string{"2_147_483_647ms", "2,147,483,647ms", "1h2,000ms", "1,000.5s"},
+		expectedExit:   0,
+		expectedStdout: "2147483647ms\n2147483647ms\n3602000ms\n1000500ms",
+		expectedStderr: "",
+	}, {
+		description:    "exponents",
+		args:           []string{"1e3ms", "1E+2s", "2.5e-3s", "0e99999s"},
+		expectedExit:   0,
+		expectedStdout: "1000ms\n100000ms\n2ms\n0ms",
+		expectedStderr: "warning: 2.5e-3s is not a whole number of milliseconds; rounded down to 2ms",
+	}, {
+		description:    "malformed separators",
+		args:           []string{"-batch", "-continue"},
+		stdin:          strings.NewReader("1,5s\n1__0s\n_1s\n10,00s\n"),
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "<stdin>:1:2: syntax error at position 2: invalid number\n1,5s\n ^\n<stdin>:2:2: syntax error at position 2: invalid number\n1__0s\n ^~\n<stdin>:3:1: syntax error at position 1: invalid number\n_1s\n^\n<stdin>:4:3: syntax error at position 3: invalid number\n10,00s\n  ^",
+	}, {
+		description:    "malformed exponent",
+		args:           []string{"1e+s"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 4: invalid number\n1e+s\n   ^",
+	}, {
+		description:    "exponent finer than a microsecond",
+		args:           []string{"1e-4ms"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 1: fraction is not a whole number of microseconds\n1e-4ms\n^~~~~~",
+	}, {
+		description:    "overflow with an exponent",
+		args:           []string{"2e40s", "1e999999999999s"},
+		expectedExit:   5,
+		expectedStdout: "",
+		expectedStderr: "overflow error at position 1\n2e40s\n^~~~~\noverflow error at position 1\n1e999999999999s\n^~~~~~~~~~~~~~~",
+	}, {
+		description:    "separated value out of range",
+		args:           []string{"2_147_483_648ms"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 1\n2_147_483_648ms\n^~~~~~~~~~~~~~~",
+	}, {
+		description:    "separators and exponents are rejected in strict mode",
+		args:           []string{"-strict", "1e3ms"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\nsyntax error at position 3: unexpected characters in single unit mode\n1e3ms\n ^^~~\nHAProxy would report: unexpected character 'e' in 'timeout'",
+	}, {
+		description:    "lenient mode accepts separators",
+		args:           []string{"-lenient", "1_000ms1h"},
+		expectedExit:   0,
+		expectedStdout: "3601000ms",
+		expectedStderr: "warning: 1_000ms1h has units out of order; read as 1h1s",
+	}, {
+		description:    "durations written out in words",
+		args:           []string{"2 hours 30 minutes", "1 day, 4 hrs", "90 seconds", "2hrs30mins", "1 minute and 1.5 seconds"},
+		expectedExit:   0,
+		expectedStdout: "9000000ms\n100800000ms\n90000ms\n9000000ms\n61500ms",
+		expectedStderr: "",
+	}, {
+		description:    "unit symbols may follow a space",
+		args:           []string{"-h", "1 d 2 h 500 ms"},
+		expectedExit:   0,
+		expectedStdout: "1d2h500ms",
+		expectedStderr: "",
+	}, {
+		description:    "unknown unit name",
+		args:           []string{"2 hours 3 fortnights"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 11: invalid unit\n2 hours 3 fortnights\n          ^~~~~~~~~~",
+	}, {
+		description:    "capitalised unit names",
+		args:           []string{"2 Hours", "90 Seconds", "1 DAY, 4 HRS"},
+		expectedExit:   0,
+		expectedStdout: "7200000ms\n90000ms\n100800000ms",
+		expectedStderr: "",
+	}, {
+		description:    "unit names out of order",
+		args:           []string{"30 minutes, 2 hours"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 15: invalid unit order\n30 minutes, 2 hours\n              ^~~~~\nwarning: units are out of order; -lenient reads this as 2h30m",
+	}, {
+		description:    "lenient mode accepts unit names in any order",
+		args:           []string{"-lenient", "30 minutes, 2 hours"},
+		expectedExit:   0,
+		expectedStdout: "9000000ms",
+		expectedStderr: "warning: 30 minutes, 2 hours has units out of order; read as 2h30m",
+	}, {
+		description:    "blank input is not read as 0ms",
+		args:           []string{" "},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 1: invalid number\n \n^",
+	}, {
+		description:    "comma-only input is not read as 0ms",
+		args:           []string{","},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 1: invalid number\n,\n^",
+	}, {
+		description:    "unit names are rejected in strict mode",
+		args:           []string{"-strict", "90 seconds"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: invalid unit\n90 seconds\n  ^\nHAProxy would report: unexpected character ' ' in 'timeout'",
+	}, {
+		description:    "ISO 8601 durations",
+		args:           []string{"PT2H30M", "P1DT12H", "PT0.5S", "PT0,5S", "P1W"},
+		expectedExit:   0,
+		expectedStdout: "9000000ms\n129600000ms\n500ms\n500ms\n604800000ms",
+		expectedStderr: "",
+	}, {
+		description:    "ISO 8601 output",
+		args:           []string{"-o", "iso8601", "0", "90s", "P1DT12H", "86400500ms", "1h1ms"},
+		expectedExit:   0,
+		expectedStdout: "PT0S\nPT1M30S\nP1DT12H\nP1DT0.5S\nPT1H0.001S",
+		expectedStderr: "",
+	}, {
+		description:    "maximum as an ISO 8601 duration",
+		args:           []string{"-m", "-o", "iso8601"},
+		expectedExit:   0,
+		expectedStdout: "P24DT20H31M23.647S",
+		expectedStderr: "",
+	}, {
+		description:    "ISO 8601 years and months are rejected",
+		args:           []string{"P1Y2M"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: years and months have no fixed length\nsyntax error at position 4: years and months have no fixed length\nP1Y2M\n ^~^~",
+	}, {
+		description:    "malformed ISO 8601 durations",
+		args:           []string{"PT", "P1DT", "PT30M2H", "PT1H2", "P1..5D"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: invalid number\nPT\n  ^\nsyntax error at position 5: invalid number\nP1DT\n    ^\nsyntax error at position 7: invalid unit order\nPT30M2H\n      ^\nsyntax error at position 6: invalid unit\nPT1H2\n     ^\nsyntax error at position 4: invalid number\nP1..5D\n   ^",
+	}, {
+		description:    "ISO 8601 duration out of range",
+		args:           []string{"P30D"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 2\nP30D\n ^~~",
+	}, {
+		description:    "ISO 8601 fraction of a microsecond",
+		args:           []string{"PT1.0000001S"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: fraction is not a whole number of microseconds\nPT1.0000001S\n  ^~~~~~~~~~",
+	}, {
+		description:    "ISO 8601 durations are rejected in strict mode",
+		args:           []string{"-strict", "PT1S"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 1: invalid number\nsyntax error at position 3: unexpected characters in single unit mode\nPT1S\n^~^~\nHAProxy would report: unexpected character 'P' in 'timeout'",
+	}, {
+		description:    "json output of an ISO 8601 error",
+		args:           []string{"-o", "json", "P1Y"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"P1Y","error":{"class":"SyntaxError","cause":"VariableLengthUnit","position":1,"message":"syntax error at position 2: years and months have no fixed length"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "Go duration syntax",
+		args:           []string{"-syntax", "go", "1h30m0s", "1.5h", "1500µs", "30m2h", "0", "+5s"},
+		expectedExit:   0,
+		expectedStdout: "5400000ms\n5400000ms\n1ms\n9000000ms\n0ms\n5000ms",
+		expectedStderr: "warning: 1500µs is not a whole number of milliseconds; rounded down to 1ms",
+	}, {
+		description:    "Go duration output",
+		args:           []string{"-o", "go", "0", "90s", "1h30m", "1d1ms"},
+		expectedExit:   0,
+		expectedStdout: "0s\n1m30s\n1h30m0s\n24h0m0.001s",
+		expectedStderr: "",
+	}, {
+		description:    "maximum in Go duration syntax",
+		args:           []string{"-m", "-o", "go"},
+		expectedExit:   0,
+		expectedStdout: "596h31m23.647s",
+		expectedStderr: "",
+	}, {
+		description:    "Go duration syntax has no days",
+		args:           []string{"-syntax", "go", "1d"},
+		expectedExit:   4,
 		expectedStdout: "",
-		expectedStderr: "overflow error at position 1",
+		expectedStderr: "syntax error at position 2: invalid unit\n1d\n ^",
+	}, {
+		description:    "Go duration syntax requires a unit",
+		args:           []string{"-syntax", "go", "1", "1.5", "."},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 2: invalid unit\n1\n ^\nsyntax error at position 4: invalid unit\n1.5\n   ^\nsyntax error at position 1: invalid number\n.\n^",
+	}, {
+		description:    "Go duration syntax errors after a unit",
+		args:           []string{"-syntax", "go", "1h-2m", "1hours"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: invalid number\n1h-2m\n  ^\nsyntax error at position 2: invalid unit\n1hours\n ^~~~~",
+	}, {
+		description:    "Go duration overflow",
+		args:           []string{"-syntax", "go", "2562048h"},
+		expectedExit:   5,
+		expectedStdout: "",
+		expectedStderr: "overflow error at position 1\n2562048h\n^~~~~~~~",
+	}, {
+		description:    "Go duration out of range",
+		args:           []string{"-syntax", "go", "--", "25h", "597h", "-1s"},
+		expectedExit:   6,
+		expectedStdout: "90000000ms",
+		expectedStderr: "range error at position 1\n597h\n^~~~\nrange error at position 1\n-1s\n^",
+	}, {
+		description:    "Go duration that rounds to zero",
+		args:           []string{"-syntax", "go", "300ns"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "underflow error: 300ns rounds to 0ms, which HAProxy treats as no timeout\n300ns\n^~~~~",
+	}, {
+		description:    "Go duration syntax cannot be strict",
+		args:           []string{"-syntax", "go", "-strict", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "-strict cannot be used with -syntax go",
+	}, {
+		description:    "invalid input syntax",
+		args:           []string{"-syntax", "yaml", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "invalid value \"yaml\" for flag -syntax: must be one of go, haproxy, systemd",
+	}, {
+		description:    "systemd time spans",
+		args:           []string{"-syntax", "systemd", "1min 30s", "2weeks", "5sec", "90", "1.5h", "1s 1s", "2 hours 5 min"},
+		expectedExit:   0,
+		expectedStdout: "90000ms\n1209600000ms\n5000ms\n90000ms\n5400000ms\n2000ms\n7500000ms",
+		expectedStderr: "",
+	}, {
+		description:    "systemd nanoseconds",
+		args:           []string{"-syntax", "systemd", "2000000 ns", "1500ns", "1500000nsec"},
+		expectedExit:   6,
+		expectedStdout: "2ms\n1ms",
+		expectedStderr: "underflow error: 1500ns rounds to 0ms, which HAProxy treats as no timeout\n1500ns\n^~~~~~\nwarning: 1500000nsec is not a whole number of milliseconds; rounded down to 1ms",
+	}, {
+		description:    "systemd nanoseconds are rounded as -round selects",
+		args:           []string{"-syntax", "systemd", "-round", "up", "1500ns"},
+		expectedExit:   0,
+		expectedStdout: "1ms",
+		expectedStderr: "warning: 1500ns is not a whole number of milliseconds; rounded up to 1ms",
+	}, {
+		description:    "systemd infinity is no timeout",
+		args:           []string{"-syntax", "systemd", "infinity"},
+		expectedExit:   0,
+		expectedStdout: "0ms",
+		expectedStderr: "",
+	}, {
+		description:    "systemd infinity for a directive that requires a timeout",
+		args:           []string{"-syntax", "systemd", "-directive", "inter", "infinity"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error: inter must be at least 1ms\ninfinity\n^~~~~~~~",
+	}, {
+		description:    "systemd months and years exceed the maximum",
+		args:           []string{"-syntax", "systemd", "1y", "1M", "4w"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 1\n1y\n^~\nrange error at position 1\n1M\n^~\nrange error at position 1\n4w\n^~",
+	}, {
+		description:    "systemd unknown units",
+		args:           []string{"-syntax", "systemd", "5 fortnights 3x"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: invalid unit\nsyntax error at position 15: invalid unit\n5 fortnights 3x\n  ^~~~~~~~~~  ^",
+	}, {
+		description:    "systemd syntax cannot be strict",
+		args:           []string{"-syntax", "systemd", "-strict", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "-strict cannot be used with -syntax systemd",
+	}, {
+		description:    "clock times",
+		args:           []string{"01:30:00", "2:05.250", "1d 04:00:00", "90:00", "0:00.001"},
+		expectedExit:   0,
+		expectedStdout: "5400000ms\n125250ms\n100800000ms\n5400000ms\n1ms",
+		expectedStderr: "",
+	}, {
+		description:    "clock output",
+		args:           []string{"-o", "clock", "0", "90s", "01:30:00", "86400500ms"},
+		expectedExit:   0,
+		expectedStdout: "00:00:00\n00:01:30\n01:30:00\n1d 00:00:00.500",
+		expectedStderr: "",
+	}, {
+		description:    "maximum as a clock time",
+		args:           []string{"-m", "-o", "clock"},
+		expectedExit:   0,
+		expectedStdout: "24d 20:31:23.647",
+		expectedStderr: "",
+	}, {
+		description:    "invalid clock fields",
+		args:           []string{"1:60", "1:5", "1:2:3:4"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: clock field must be two digits from 00 to 59\n1:60\n  ^~\nsyntax error at position 3: clock field must be two digits from 00 to 59\n1:5\n  ^\nsyntax error at position 3: clock field must be two digits from 00 to 59\nsyntax error at position 5: clock field must be two digits from 00 to 59\nsyntax error at position 6: clock field must be two digits from 00 to 59\n1:2:3:4\n  ^~^^",
+	}, {
+		description:    "malformed clock times",
+		args:           []string{"1:30x", ":30", "1:30."},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 5: invalid unit\n1:30x\n    ^\nsyntax error at position 1: invalid number\n:30\n^\nsyntax error at position 6: invalid number\n1:30.\n     ^",
+	}, {
+		description:    "clock time out of range",
+		args:           []string{"596:31:23.648"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 8\n596:31:23.648\n       ^~~~~~",
+	}, {
+		description:    "clock times are rejected in strict mode",
+		args:           []string{"-strict", "01:30"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: invalid unit\nsyntax error at position 4: unexpected characters in single unit mode\n01:30\n  ^^~\nHAProxy would report: unexpected character ':' in 'timeout'",
+	}, {
+		description:    "json output of a clock error",
+		args:           []string{"-o", "json", "1:60"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"1:60","error":{"class":"SyntaxError","cause":"InvalidClockField","position":2,"message":"syntax error at position 3: clock field must be two digits from 00 to 59"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "arithmetic expressions",
+		args:           []string{"2h - 15m", "3 * 30s", "(1m30s) / 2", "max(30s, 2 * 10s)", "min(1m, 45s)", "2 * 1000", "2 hours 30 minutes - 15 min"},
+		expectedExit:   0,
+		expectedStdout: "6300000ms\n90000ms\n45000ms\n30000ms\n45000ms\n2000ms\n8100000ms",
+		expectedStderr: "",
+	}, {
+		description:    "expression with a plain number read in the directive's unit",
+		args:           []string{"-directive", "tune.ssl.lifetime", "300 / 2"},
+		expectedExit:   0,
+		expectedStdout: "150000ms",
+		expectedStderr: "",
+	}, {
+		description:    "expression out of range at an intermediate step",
+		args:           []string{"15m - 2h", "20d + 5d", "30d / 2"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 5\n15m - 2h\n    ^\nrange error at position 5\n20d + 5d\n    ^\nrange error at position 1\n30d / 2\n^~~",
+	}, {
+		description:    "plain number expressions report problems at the operator",
+		args:           []string{"10 - 20", "(10 - 20)", "1 / 3"},
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "range error at position 4\n10 - 20\n   ^\nrange error at position 5\n(10 - 20)\n    ^\nsyntax error at position 3: fraction is not a whole number of microseconds\n1 / 3\n  ^",
+	}, {
+		description:    "plain numbers as function arguments",
+		args:           []string{"max(3, 2)", "min(1000,2000)"},
+		expectedExit:   0,
+		expectedStdout: "3ms\n1000ms",
+		expectedStderr: "",
+	}, {
+		description:    "expression with an inexact result",
+		args:           []string{"10s / 3"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 5: fraction is not a whole number of microseconds\n10s / 3\n    ^",
+	}, {
+		description:    "expressions with invalid operands",
+		args:           []string{"2s * 3s", "1h / 30m", "1s / (2 - 2)", "1x + 2y"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 6: cannot multiply two durations\n2s * 3s\n     ^~\nsyntax error at position 6: cannot divide by a duration\n1h / 30m\n     ^~~\nsyntax error at position 6: division by zero\n1s / (2 - 2)\n     ^\nsyntax error at position 2: invalid unit\nsyntax error at position 7: invalid unit\n1x + 2y\n ^    ^",
+	}, {
+		description:    "malformed expressions",
+		args:           []string{"(1s + 2s", "1s + 2s)", "foo(1s)", "1s + ", "01:30 + 1s"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 1: unbalanced parenthesis\n(1s + 2s\n^\nsyntax error at position 8: unbalanced parenthesis\n1s + 2s)\n       ^\nsyntax error at position 1: unknown function\nfoo(1s)\n^~~~\nsyntax error at position 6: invalid number\n1s + \n     ^\nsyntax error at position 3: expected an operator\n01:30 + 1s\n  ^",
+	}, {
+		description:    "expressions are rejected in strict mode",
+		args:           []string{"-strict", "2h-15m"},
+		expectedExit:   4,
+		expectedStdout: "",
+		expectedStderr: "syntax error at position 3: unexpected characters in single unit mode\n2h-15m\n  ^\nHAProxy would report: unexpected character '-' in 'timeout'",
+	}, {
+		description:    "json output of an expression error",
+		args:           []string{"-o", "json", "1s / 0"},
+		expectedExit:   4,
+		expectedStdout: `{"input":"1s / 0","error":{"class":"SyntaxError","cause":"DivisionByZero","position":5,"message":"syntax error at position 6: division by zero"}}`,
+		expectedStderr: "",
+	}, {
+		description:    "validate only",
+		args:           []string{"-q", "1s", "2m"},
+		expectedExit:   0,
+		expectedStdout: "",
+		expectedStderr: "",
+	}, {
+		description:    "validate only reports the first failure",
+		args:           []string{"-q", "1s", "9223372036855ms", "2h3x"},
+		expectedExit:   5,
+		expectedStdout: "",
+		expectedStderr: "",
+	}, {
+		description:    "validate only with batch input",
+		args:           []string{"-q", "-batch", "-continue"},
+		stdin:          strings.NewReader("1s\n30d\nx\n"),
+		expectedExit:   6,
+		expectedStdout: "",
+		expectedStderr: "",
+	}, {
+		description:    "validate only still reports invalid flags",
+		args:           []string{"-q", "-round", "sideways", "1s"},
+		expectedExit:   2,
+		expectedStdout: "",
+		expectedStderr: "invalid value \"sideways\" for flag -round: must be one of down, error, nearest, up",
 	}, {
 		description:    "empty string from stdin",
 		stdin:          &emptyStringReader{},
@@ -231,8 +1190,8 @@ func TestConvertDurationPrintFailure(t *testing.T) {
 
 	// Verify that the mock exit handler was triggered with the
 	// expected exit code.
-	if !mockExitHandler.Exited || mockExitHandler.Code != 1 {
-		t.Errorf("Expected exit with code 1, got exit %v with code %d", mockExitHandler.Exited, mockExitHandler.Code)
+	if !mockExitHandler.Exited || mockExitHandler.Code != 7 {
+		t.Errorf("Expected exit with code 7, got exit %v with code %d", mockExitHandler.Exited, mockExitHandler.Code)
 	}
 }
 
@@ -252,8 +1211,8 @@ func TestConvertDurationPrintlnFailure(t *testing.T) {
 
 	// Verify that the mock exit handler was triggered with the
 	// expected exit code.
-	if !mockExitHandler.Exited || mockExitHandler.Code != 1 {
-		t.Errorf("Expected exit with code 1, got exit %v with code %d", mockExitHandler.Exited, mockExitHandler.Code)
+	if !mockExitHandler.Exited || mockExitHandler.Code != 7 {
+		t.Errorf("Expected exit with code 7, got exit %v with code %d", mockExitHandler.Exited, mockExitHandler.Code)
 	}
 }
 
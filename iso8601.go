@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/frobware/comptime"
+)
+
+// variableLengthUnit is the cause of a syntax error for an ISO 8601
+// duration with years or months, which have no fixed length and so
+// cannot be converted to a timeout.
+const variableLengthUnit = inexactFraction + 1
+
+// iso8601Designator is a designator of an ISO 8601 duration, such as
+// the "H" of "PT2H", and the duration it stands for. A zero duration
+// marks a designator of variable length.
+type iso8601Designator struct {
+	designator byte
+	duration   time.Duration
+}
+
+// iso8601DateDesignators and iso8601TimeDesignators are the
+// designators allowed before and after the "T" of an ISO 8601
+// duration, in the order they must appear.
+var (
+	iso8601DateDesignators = []iso8601Designator{
+		{'Y', 0},
+		{'M', 0},
+		{'W', 7 * 24 * time.Hour},
+		{'D', 24 * time.Hour},
+	}
+	iso8601TimeDesignators = []iso8601Designator{
+		{'H', time.Hour},
+		{'M', time.Minute},
+		{'S', time.Second},
+	}
+)
+
+// isISO8601 reports whether input is written as an ISO 8601
+// duration, which always starts with "P".
+func isISO8601(input string) bool {
+	return strings.HasPrefix(input, "P")
+}
+
+// scanISO8601 parses input as an ISO 8601 duration, such as "PT2H30M",
+// "P1DT12H" or "PT0.5S", and returns the total along with every
+// problem found, in order of position, as scanDuration does. Any value
+// may have a fraction, written with "." or ",", provided the total is
+// a whole number of microseconds. Years and months are rejected, as
+// their length varies, and weeks are read as 7 days.
+func scanISO8601(input string, d *directive) (time.Duration, []error) {
+	nextValue := func(position int) int {
+		for position < len(input) && !isDigit(input[position]) && input[position] != 'T' {
+			position++
+		}
+		return position
+	}
+
+	var errs []error
+	total := durationTotal{max: d.max}
+	designators := iso8601DateDesignators
+	next := 0
+	inTime := false
+	values := 0
+
+	position := 1
+	for position < len(input) {
+		if input[position] == 'T' && !inTime {
+			designators, next, inTime, values = iso8601TimeDesignators, 0, true, 0
+			position++
+			continue
+		}
+
+		numStart := position
+		number, end, bad := scanISO8601Number(input, position)
+		if bad >= 0 {
+			errs = append(errs, syntaxError(comptime.InvalidNumber, bad))
+			position = nextValue(bad + 1)
+			continue
+		}
+		values++
+
+		position = end
+		if position == len(input) {
+			errs = append(errs, syntaxError(comptime.InvalidUnit, position))
+			break
+		}
+
+		index := -1
+		for i, designator := range designators {
+			if designator.designator == input[position] {
+				index = i
+			}
+		}
+		if index < 0 {
+			errs = append(errs, syntaxError(comptime.InvalidUnit, position))
+			position = nextValue(position + 1)
+			continue
+		}
+		position++
+
+		if index < next {
+			errs = append(errs, syntaxError(comptime.InvalidUnitOrder, position-1))
+			continue
+		}
+		next = index + 1
+
+		if designators[index].duration == 0 {
+			errs = append(errs, syntaxError(variableLengthUnit, numStart))
+			continue
+		}
+
+		composite, err := number.duration(designators[index].duration, numStart)
+		if err == nil {
+			err = total.add(composite, numStart)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// "P" and "T" must each be followed by at least one value.
+	if values == 0 && len(errs) == 0 {
+		errs = append(errs, syntaxError(comptime.InvalidNumber, len(input)))
+	}
+
+	return total.total, errs
+}
+
+// scanISO8601Number scans the number of an ISO 8601 duration starting
+// at position in input: digits with an optional fraction after a "."
+// or ",". It returns the number, the position just past it, and -1,
+// or the position of the first character that makes the number
+// malformed.
+func scanISO8601Number(input string, position int) (decimal, int, int) {
+	start := position
+	for position < len(input) && isDigit(input[position]) {
+		position++
+	}
+	if position == start {
+		return decimal{}, position, position
+	}
+	digits := input[start:position]
+
+	if position < len(input) && (input[position] == '.' || input[position] == ',') {
+		position++
+		fracStart := position
+		for position < len(input) && isDigit(input[position]) {
+			position++
+		}
+		if position == fracStart {
+			return decimal{}, position, position
+		}
+		return decimal{digits: digits + input[fracStart:position], exponent: fracStart - position}, position, -1
+	}
+
+	return decimal{digits: digits}, position, -1
+}
+
+// formatISO8601Duration returns duration as an ISO 8601 duration, for example
+// "PT1H30M" for 5400000ms or "P1DT0.5S" for 86400500ms. Any
+// sub-millisecond component is ignored.
+func formatISO8601Duration(duration time.Duration) string {
+	parts := splitDuration(duration)
+
+	var b strings.Builder
+	b.WriteString("P")
+	if parts.days > 0 {
+		fmt.Fprintf(&b, "%dD", parts.days)
+	}
+	if parts.hours > 0 || parts.minutes > 0 || parts.seconds > 0 || parts.milliseconds > 0 {
+		b.WriteString("T")
+	}
+	if parts.hours > 0 {
+		fmt.Fprintf(&b, "%dH", parts.hours)
+	}
+	if parts.minutes > 0 {
+		fmt.Fprintf(&b, "%dM", parts.minutes)
+	}
+	switch {
+	case parts.milliseconds > 0:
+		fraction := strings.TrimRight(fmt.Sprintf("%03d", parts.milliseconds), "0")
+		fmt.Fprintf(&b, "%d.%sS", parts.seconds, fraction)
+	case parts.seconds > 0:
+		fmt.Fprintf(&b, "%dS", parts.seconds)
+	}
+
+	if b.Len() == 1 {
+		return "PT0S"
+	}
+	return b.String()
+}
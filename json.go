@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/frobware/comptime"
+)
+
+// syntaxErrorCauses maps each comptime.SyntaxErrorCause to the name
+// reported in JSON output.
+var syntaxErrorCauses = map[comptime.SyntaxErrorCause]string{
+	comptime.InvalidNumber:                        "InvalidNumber",
+	comptime.InvalidUnit:                          "InvalidUnit",
+	comptime.InvalidUnitOrder:                     "InvalidUnitOrder",
+	comptime.UnexpectedCharactersInSingleUnitMode: "UnexpectedCharactersInSingleUnitMode",
+	inexactFraction:                               "InexactFraction",
+	variableLengthUnit:                            "VariableLengthUnit",
+	invalidClockField:                             "InvalidClockField",
+	invalidOperator:                               "InvalidOperator",
+	unbalancedParenthesis:                         "UnbalancedParenthesis",
+	unknownFunction:                               "UnknownFunction",
+	durationProduct:                               "DurationProduct",
+	durationDivisor:                               "DurationDivisor",
+	divisionByZero:                                "DivisionByZero",
+}
+
+// jsonUnits is a duration broken down into the units HAProxy accepts,
+// as formatDuration prints it.
+type jsonUnits struct {
+	Days         int64 `json:"days"`
+	Hours        int64 `json:"hours"`
+	Minutes      int64 `json:"minutes"`
+	Seconds      int64 `json:"seconds"`
+	Milliseconds int64 `json:"milliseconds"`
+}
+
+// jsonError describes a failed conversion so that callers can act on
+// it without parsing diagnostic text.
+type jsonError struct {
+	// Class names the kind of failure, for example "SyntaxError",
+	// "OverflowError" or "RangeError".
+	Class string `json:"class"`
+
+	// Cause is the reason for a SyntaxError, for example
+	// "InvalidUnit".
+	Cause string `json:"cause,omitempty"`
+
+	// Position is the 0-based byte offset in the input at which
+	// the error was detected, if the error has one.
+	Position *int `json:"position,omitempty"`
+
+	// Message is the error as it is printed in text output.
+	Message string `json:"message"`
+
+	// HAProxy is the message HAProxy itself would print for the
+	// value, in strict mode.
+	HAProxy string `json:"haproxy,omitempty"`
+}
+
+// jsonResult is the object written for each value by -o json.
+// Exactly one of Milliseconds and Error is set.
+type jsonResult struct {
+	Input        string     `json:"input"`
+	Milliseconds *int64     `json:"milliseconds,omitempty"`
+	Human        string     `json:"human,omitempty"`
+	Units        *jsonUnits `json:"units,omitempty"`
+	Warning      string     `json:"warning,omitempty"`
+	Error        *jsonError `json:"error,omitempty"`
+
+	// Errors lists every problem found in the input, the first
+	// of which is Error, when there is more than one.
+	Errors []*jsonError `json:"errors,omitempty"`
+
+	// Suggestion is a corrected form of an input rejected for
+	// its syntax, and Fixed is true if -fix converted it in
+	// place of the input.
+	Suggestion string `json:"suggestion,omitempty"`
+	Fixed      bool   `json:"fixed,omitempty"`
+
+	// Reordered is the canonical form of an input whose units
+	// are out of order or repeated.
+	Reordered string `json:"reordered,omitempty"`
+}
+
+// newJSONResult returns the JSON description of converting input,
+// given the duration and error returned by convertValue. A
+// *precisionWarning is reported as a warning alongside the value.
+func newJSONResult(input string, duration time.Duration, err error, opts parseOptions) *jsonResult {
+	result := &jsonResult{Input: input}
+
+	var warning *precisionWarning
+	if errors.As(err, &warning) {
+		result.Warning = warning.Error()
+		err = nil
+	}
+
+	if err != nil {
+		if errs := splitErrors(err); len(errs) > 1 {
+			for _, err := range errs {
+				result.Errors = append(result.Errors, newJSONError(err))
+			}
+		}
+		result.Error = newJSONError(firstError(err))
+		if opts.strict {
+			result.Error.HAProxy = haproxyMessage(input, opts.directive, err)
+		}
+		return result
+	}
+
+	ms := duration.Milliseconds()
+	parts := splitDuration(duration)
+	result.Milliseconds = &ms
+	result.Human = formatDuration(duration)
+	result.Units = &jsonUnits{
+		Days:         parts.days,
+		Hours:        parts.hours,
+		Minutes:      parts.minutes,
+		Seconds:      parts.seconds,
+		Milliseconds: parts.milliseconds,
+	}
+
+	return result
+}
+
+// newJSONError classifies err for JSON output.
+func newJSONError(err error) *jsonError {
+	jsonErr := &jsonError{Class: "Error", Message: err.Error()}
+
+	var syntaxErr *comptime.SyntaxError
+	var overflowErr *comptime.OverflowError
+	var rangeErr *comptime.RangeError
+	var emptyErr *emptyValueError
+	var minimumErr *minimumError
+	var precisionErr *precisionError
+	var underflowErr *underflowError
+	var tooLongErr *inputTooLongError
+	var diagnosticErr *diagnosticError
+
+	switch {
+	case errors.As(err, &diagnosticErr):
+		jsonErr.Class = diagnosticErr.class
+		jsonErr.Cause = syntaxErrorCauses[diagnosticErr.cause]
+	case errors.As(err, &syntaxErr):
+		jsonErr.Class = "SyntaxError"
+		jsonErr.Cause = syntaxErrorCauses[syntaxErr.Cause()]
+	case errors.As(err, &emptyErr):
+		jsonErr.Class = "SyntaxError"
+		jsonErr.Cause = "EmptyValue"
+	case errors.As(err, &overflowErr):
+		jsonErr.Class = "OverflowError"
+	case errors.As(err, &rangeErr):
+		jsonErr.Class = "RangeError"
+	case errors.As(err, &minimumErr):
+		jsonErr.Class = "MinimumError"
+	case errors.As(err, &precisionErr):
+		jsonErr.Class = "PrecisionError"
+	case errors.As(err, &underflowErr):
+		jsonErr.Class = "UnderflowError"
+	case errors.As(err, &tooLongErr):
+		jsonErr.Class = "InputTooLongError"
+	}
+
+	var posErr interface {
+		Position() int
+	}
+	if errors.As(err, &posErr) && posErr != nil {
+		position := posErr.Position()
+		jsonErr.Position = &position
+	}
+
+	return jsonErr
+}
+
+// writeJSON writes result to w as a single line of JSON.
+func writeJSON(w io.Writer, exitHandler ExitHandler, result *jsonResult) {
+	// A jsonResult holds only strings and integers, which always
+	// marshal successfully.
+	data, _ := json.Marshal(result)
+	safeFprintln(w, exitHandler, string(data))
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/frobware/comptime"
+)
+
+// segmentValue is the value of one number and unit pair of a
+// duration, such as "30m" in "30m2h".
+type segmentValue struct {
+	// position is the 0-based byte offset of the number in the
+	// input.
+	position int
+	value    time.Duration
+}
+
+// splitSegments parses input as a sequence of number and unit pairs
+// that may appear in any order, and may repeat a unit, as in "30m2h"
+// or "1h1h". A number may be written in any form scanDecimal accepts,
+// as in "30m1.5h", and a number without a unit is read in the
+// directive's default unit. It returns false if any pair is
+// malformed.
+func splitSegments(input string, d *directive) ([]segmentValue, bool) {
+	// Each pair is range checked by sumSegments once its
+	// position in the total is known.
+	unbounded := *d
+	unbounded.max = math.MaxInt64
+
+	var segments []segmentValue
+
+	for position := 0; position < len(input); {
+		start := position
+		_, end, bad := scanDecimal(input, position)
+		if bad >= 0 {
+			return nil, false
+		}
+		position = end
+		for position < len(input) && !isDigit(input[position]) && input[position] != '.' {
+			position++
+		}
+
+		value, errs := scanDuration(input[start:position], &unbounded, comptime.ParseModeSingleUnit, true)
+		if len(errs) > 0 {
+			return nil, false
+		}
+		segments = append(segments, segmentValue{position: start, value: value})
+	}
+
+	return segments, true
+}
+
+// sumSegments returns the total of segments. The total is checked
+// against d.max as each segment is added, in the order written, and
+// the error reports the segment that took it out of range.
+func sumSegments(segments []segmentValue, d *directive) (time.Duration, error) {
+	var total time.Duration
+	for _, s := range segments {
+		if total > math.MaxInt64-s.value {
+			return 0, &diagnosticError{class: "OverflowError", position: s.position}
+		}
+		if total+s.value > d.max {
+			return 0, &diagnosticError{class: "RangeError", position: s.position}
+		}
+		total += s.value
+	}
+	return total, nil
+}
+
+// isUnitOrderError reports whether the first problem in err is a unit
+// written out of order, or repeated.
+func isUnitOrderError(err error) bool {
+	var syntaxErr *comptime.SyntaxError
+	var diagErr *diagnosticError
+	switch err = firstError(err); {
+	case errors.As(err, &syntaxErr):
+		return syntaxErr.Cause() == comptime.InvalidUnitOrder
+	case errors.As(err, &diagErr):
+		return diagErr.class == "SyntaxError" && diagErr.cause == comptime.InvalidUnitOrder
+	}
+	return false
+}
+
+// parseAnyOrder parses input, which comptime.ParseDuration rejected
+// with err, accepting units in any order and summing repeated units.
+// It returns false if err is not a unit order problem or input has
+// other problems too; otherwise the total, or a range or overflow
+// error, is returned as sumSegments does.
+func parseAnyOrder(input string, d *directive, err error) (time.Duration, bool, error) {
+	if !isUnitOrderError(err) {
+		return 0, false, nil
+	}
+
+	segments, ok := splitSegments(input, d)
+	if !ok {
+		return 0, false, nil
+	}
+
+	duration, err := sumSegments(segments, d)
+	return duration, true, err
+}
+
+// reorderedForm returns the canonical form of input if its units are
+// out of order or repeated but it is otherwise valid, for example
+// "2h30m" for "30m2h" or "2h" for "1h1h". An empty string is returned
+// if input is already in order or cannot be read in any order, and in
+// strict mode or with another syntax, where the order is not in
+// question.
+func reorderedForm(input string, opts parseOptions) string {
+	if opts.strict || opts.syntax != syntaxHAProxy {
+		return ""
+	}
+
+	d := opts.directive
+	if d == nil {
+		d = defaultDirective
+	}
+
+	unbounded := *d
+	unbounded.max = math.MaxInt64
+	_, errs := scanDuration(input, &unbounded, comptime.ParseModeMultiUnit, true)
+	duration, ok, err := parseAnyOrder(input, d, joinErrors(errs))
+	if !ok || err != nil {
+		return ""
+	}
+
+	return canonicalForm(duration)
+}
+
+// canonicalForm returns duration as formatDuration does, but keeping
+// any sub-millisecond component as microseconds so that the result
+// has exactly the same value.
+func canonicalForm(duration time.Duration) string {
+	micros := (duration % time.Millisecond) / time.Microsecond
+	switch {
+	case micros == 0:
+		return formatDuration(duration)
+	case duration < time.Millisecond:
+		return fmt.Sprintf("%dus", micros)
+	}
+	return fmt.Sprintf("%s%dus", formatDuration(duration-duration%time.Millisecond), micros)
+}
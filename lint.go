@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// lintConfig checks every time-valued directive in the configuration
+// held in data, writing a diagnostic to w for each value that is
+// malformed, outside the range HAProxy accepts for its directive, or
+// which HAProxy would truncate to zero. Values that HAProxy would
+// truncate to a non-zero number of milliseconds produce a warning
+// that is not counted as a problem. Diagnostics are prefixed with
+// filename:line:col and followed by the offending line and a caret
+// pointing at the problem. It returns the number of problems found.
+func lintConfig(w io.Writer, exitHandler ExitHandler, filename, data string, opts parseOptions) int {
+	problems := 0
+
+	for _, line := range parseConfig(data) {
+		for _, tv := range findTimeValues(line) {
+			if _, ok := checkTimeValue(w, exitHandler, filename, line, tv, opts); !ok {
+				problems++
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkTimeValue converts the time value tv, found on the given line,
+// to whole milliseconds using opts, with the directive replaced by
+// that of tv. Problems, including words following the value, are
+// written to w with printConfigError, as are warnings about lost
+// precision. It returns the converted value and true, or false if
+// the value is unusable.
+func checkTimeValue(w io.Writer, exitHandler ExitHandler, filename string, line configLine, tv timeValue, opts parseOptions) (time.Duration, bool) {
+	if tv.token.text == "" {
+		printConfigError(w, exitHandler, filename, line, tv, errMissingTimeValue)
+		return 0, false
+	}
+
+	if tv.extra != nil {
+		extra := timeValue{directive: tv.directive, token: *tv.extra}
+		printConfigError(w, exitHandler, filename, line, extra, fmt.Errorf("unexpected argument %q after the time value", tv.extra.text))
+		return 0, false
+	}
+
+	opts.directive = tv.directive
+	duration, err := convertValue(tv.token.text, opts)
+
+	var warning *precisionWarning
+	if errors.As(err, &warning) {
+		printConfigError(w, exitHandler, filename, line, tv, warning)
+		return duration, true
+	}
+
+	if err != nil {
+		printConfigError(w, exitHandler, filename, line, tv, err)
+		return 0, false
+	}
+
+	return duration, true
+}
+
+// printConfigError writes err, found while checking the time value
+// tv on the given line, in the file:line:col format used by
+// compilers, followed by the line itself and a caret under the
+// offending character. Several errors in the same value are reported
+// together, with a caret under each.
+func printConfigError(w io.Writer, exitHandler ExitHandler, filename string, line configLine, tv timeValue, err error) {
+	var posErr interface {
+		Position() int
+	}
+	hasPosition := errors.As(err, &posErr) && posErr != nil
+
+	errs := splitErrors(err)
+	columns := make([]int, len(errs))
+	for i, err := range errs {
+		columns[i] = tv.token.start
+		if hasPosition {
+			columns[i] = tv.token.column(errorPosition(err))
+		}
+		errs[i] = fmt.Errorf("%s:%d:%d: %s: %w", filename, line.number, columns[i]+1, tv.directive.name, err)
+	}
+
+	printErrorsWithPositions(w, exitHandler, line.text, errs, columns)
+}
+
+// readConfig returns the contents of the named configuration file,
+// reading from rdr when the name is "-".
+func readConfig(rdr io.Reader, filename string) (string, error) {
+	var data []byte
+	var err error
+
+	if filename == "-" {
+		data, err = io.ReadAll(rdr)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// configName returns the name used for filename in diagnostics:
+// "<stdin>" for "-", otherwise filename unchanged.
+func configName(filename string) string {
+	if filename == "-" {
+		return "<stdin>"
+	}
+	return filename
+}
+
+// lintCommand implements "haproxytime lint [-composite] [<file>...]".
+// Each named HAProxy configuration file, or stdin if none is given,
+// is scanned for time-valued directives and every value is checked
+// as HAProxy would check it, in its own single-unit syntax. With
+// -composite, values may instead combine several units, as in
+// "1m30s", which HAProxy itself rejects, but the other extensions
+// accepted when converting a duration, such as "1.5h", are still
+// reported as problems. The -strict flag, which
+// used to select HAProxy's syntax, is still accepted. It returns
+// exitSuccess if all values are valid, exitFailure if any problem was
+// found or a file could not be read, and exitUsage for invalid flags.
+func lintCommand(rdr io.Reader, stdout, stderr io.Writer, args []string, exitHandler ExitHandler) int {
+	fs := flag.NewFlagSet("haproxytime lint", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var strict, composite bool
+	fs.BoolVar(&strict, "strict", true, "Accept only HAProxy's single-unit time syntax (the default)")
+	fs.BoolVar(&composite, "composite", false, "Accept values combining several units, such as 1m30s")
+
+	if err := fs.Parse(args); err != nil {
+		safeFprintln(stderr, exitHandler, err)
+		return exitUsage
+	}
+
+	opts := parseOptions{strict: !composite, plain: true}
+
+	filenames := fs.Args()
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+
+	status := exitSuccess
+
+	for _, filename := range filenames {
+		data, err := readConfig(rdr, filename)
+		if err != nil {
+			safeFprintln(stderr, exitHandler, err)
+			status = exitFailure
+			continue
+		}
+
+		name := configName(filename)
+		if lintConfig(stderr, exitHandler, name, data, opts) > 0 {
+			status = exitFailure
+		}
+	}
+
+	return status
+}
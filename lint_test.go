@@ -0,0 +1,228 @@
+package main_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/frobware/haproxytime"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		config         string
+		expectedExit   int
+		expectedStderr string
+	}{{
+		description: "valid configuration",
+		config: `
+defaults
+    timeout client 30s
+    timeout server 1m
+    timeout connect 5000
+    timeout http-request 2147483647ms
+`[1:],
+		expectedExit:   0,
+		expectedStderr: "",
+	}, {
+		description: "value exceeds HAProxy's maximum duration",
+		config: `
+defaults
+    timeout tunnel 30d
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:20: timeout tunnel: range error at position 1\n    timeout tunnel 30d\n                   ^~~",
+	}, {
+		description: "syntax error",
+		config: `
+backend be
+	timeout server 2x # comment
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:18: timeout server: syntax error at position 2: invalid unit\n\ttimeout server 2x # comment\n\t                ^",
+	}, {
+		description: "error within a quoted value",
+		config: `
+frontend fe
+    timeout client "1x"
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:22: timeout client: syntax error at position 2: invalid unit\n    timeout client \"1x\"\n                     ^",
+	}, {
+		description: "missing value",
+		config: `
+defaults
+    timeout client
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:19: timeout client: missing time value\n    timeout client\n                  ^",
+	}, {
+		description: "empty quoted value",
+		config: `
+defaults
+    timeout client ""
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:20: timeout client: missing time value\n    timeout client \"\"\n                   ^",
+	}, {
+		description: "words after the value",
+		config: `
+defaults
+    timeout connect 1s extra
+    timeout server 2h - 15m
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:24: timeout connect: unexpected argument \"extra\" after the time value\n    timeout connect 1s extra\n                       ^~~~~\n<stdin>:3:23: timeout server: unexpected argument \"-\" after the time value\n    timeout server 2h - 15m\n                      ^",
+	}, {
+		description: "every problem is reported",
+		config: `
+defaults
+    timeout client 30d
+    timeout server 1x
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:20: timeout client: range error at position 1\n    timeout client 30d\n                   ^~~\n<stdin>:3:21: timeout server: syntax error at position 2: invalid unit\n    timeout server 1x\n                    ^",
+	}, {
+		description: "directive specific limits",
+		config: `
+global
+    tune.idletimer 2m
+backend be
+    server s1 10.0.0.1:80 check inter 0
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:20: tune.idletimer: range error at position 1\n    tune.idletimer 2m\n                   ^~\n<stdin>:4:39: inter: range error: inter must be at least 1ms\n    server s1 10.0.0.1:80 check inter 0\n                                      ^",
+	}, {
+		description: "sub-millisecond values",
+		config: `
+defaults
+    timeout client 1500us
+    timeout server 500us
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:20: timeout client: warning: 1500us is not a whole number of milliseconds; rounded down to 1ms\n    timeout client 1500us\n                   ^~~~~~\n<stdin>:3:20: timeout server: underflow error: 500us rounds to 0ms, which HAProxy treats as no timeout\n    timeout server 500us\n                   ^~~~~",
+	}, {
+		description: "directives stored in seconds",
+		config: `
+global
+    tune.ssl.lifetime 1500ms
+    tune.ssl.lifetime 500ms
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:23: tune.ssl.lifetime: warning: 1500ms is not a whole number of seconds; rounded down to 1000ms\n    tune.ssl.lifetime 1500ms\n                      ^~~~~~\n<stdin>:3:23: tune.ssl.lifetime: underflow error: 500ms rounds to 0s, which HAProxy treats as no timeout\n    tune.ssl.lifetime 500ms\n                      ^~~~~",
+	}, {
+		description: "composite values are rejected as HAProxy rejects them",
+		config: `
+defaults
+    timeout client 90s
+    timeout server 1m30s
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:3:22: timeout server: syntax error at position 3: unexpected characters in single unit mode\n    timeout server 1m30s\n                     ^~~",
+	}, {
+		description: "composite values are accepted with -composite",
+		args:        []string{"-composite"},
+		config: `
+defaults
+    timeout client 1m30s
+    timeout http-request 24d20h31m23s647ms
+`[1:],
+		expectedExit:   0,
+		expectedStderr: "",
+	}, {
+		description: "extended syntax is rejected with -composite",
+		args:        []string{"-composite"},
+		config: `
+defaults
+    timeout client 1.5h
+    timeout server "2h - 15m"
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:21: timeout client: syntax error at position 2: invalid unit\n    timeout client 1.5h\n                    ^\n<stdin>:3:23: timeout server: syntax error at position 3: invalid number\n    timeout server \"2h - 15m\"\n                      ^",
+	}, {
+		description: "-strict is still accepted",
+		args:        []string{"-strict"},
+		config: `
+defaults
+    timeout client 90s
+`[1:],
+		expectedExit:   0,
+		expectedStderr: "",
+	}, {
+		description: "every problem in a value is reported",
+		args:        []string{"-composite"},
+		config: `
+defaults
+    timeout client 1x2h3y
+`[1:],
+		expectedExit:   1,
+		expectedStderr: "<stdin>:2:21: timeout client: syntax error at position 2: invalid unit\n<stdin>:2:25: timeout client: syntax error at position 6: invalid unit\n    timeout client 1x2h3y\n                    ^   ^",
+	}, {
+		description: "unrelated keywords are ignored",
+		config: `
+global
+    maxconn 1x
+    timeout unknown 1x
+`[1:],
+		expectedExit:   0,
+		expectedStderr: "",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			mockExitHandler := &mockExitHandler{}
+
+			args := append([]string{"lint"}, tc.args...)
+			exitCode := cmd.ConvertDuration(strings.NewReader(tc.config), stdout, stderr, args, mockExitHandler)
+
+			if exitCode != tc.expectedExit {
+				t.Errorf("Expected exit code %d, but got %d", tc.expectedExit, exitCode)
+			}
+
+			if stdout.Len() != 0 {
+				t.Errorf("Expected no stdout, got:\n<<<%s>>>", stdout.String())
+			}
+
+			actualStderr := strings.TrimSuffix(stderr.String(), "\n")
+			if actualStderr != tc.expectedStderr {
+				t.Errorf("Expected stderr:\n<<<%s>>>\nBut got:\n<<<%s>>>", tc.expectedStderr, actualStderr)
+			}
+		})
+	}
+}
+
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.cfg")
+	bad := filepath.Join(dir, "bad.cfg")
+
+	if err := os.WriteFile(good, []byte("defaults\n    timeout client 30s\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("defaults\n    timeout client 30d\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := &bytes.Buffer{}
+	exitCode := cmd.ConvertDuration(nil, &bytes.Buffer{}, stderr, []string{"lint", good, bad, filepath.Join(dir, "missing.cfg")}, &mockExitHandler{})
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, but got %d", exitCode)
+	}
+
+	for _, expected := range []string{bad + ":2:20: timeout client: range error at position 1", "error reading: "} {
+		if !strings.Contains(stderr.String(), expected) {
+			t.Errorf("Expected stderr to contain %q, got:\n%s", expected, stderr.String())
+		}
+	}
+
+	if strings.Contains(stderr.String(), good) {
+		t.Errorf("Expected no diagnostics for %s, got:\n%s", good, stderr.String())
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/frobware/comptime"
+)
+
+// unitNames maps the unit names accepted outside strict mode, as in
+// "2 hours 30 minutes" or "1 day, 4 hrs", to the unit they name.
+// The symbols comptime accepts are included so that they may also be
+// written with a space, as in "90 s".
+var unitNames = map[string]comptime.Unit{
+	"d":            comptime.Day,
+	"day":          comptime.Day,
+	"days":         comptime.Day,
+	"h":            comptime.Hour,
+	"hr":           comptime.Hour,
+	"hrs":          comptime.Hour,
+	"hour":         comptime.Hour,
+	"hours":        comptime.Hour,
+	"m":            comptime.Minute,
+	"min":          comptime.Minute,
+	"mins":         comptime.Minute,
+	"minute":       comptime.Minute,
+	"minutes":      comptime.Minute,
+	"s":            comptime.Second,
+	"sec":          comptime.Second,
+	"secs":         comptime.Second,
+	"second":       comptime.Second,
+	"seconds":      comptime.Second,
+	"ms":           comptime.Millisecond,
+	"msec":         comptime.Millisecond,
+	"msecs":        comptime.Millisecond,
+	"millisecond":  comptime.Millisecond,
+	"milliseconds": comptime.Millisecond,
+	"us":           comptime.Microsecond,
+	"usec":         comptime.Microsecond,
+	"usecs":        comptime.Microsecond,
+	"microsecond":  comptime.Microsecond,
+	"microseconds": comptime.Microsecond,
+}
+
+// isCompact reports whether input consists only of the digits and
+// unit symbols of comptime's grammar, as in "2h30m", and so needs
+// none of the extensions scanDuration provides.
+func isCompact(input string) bool {
+	for _, r := range input {
+		if !strings.ContainsRune("0123456789dhmsu", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBlank reports whether c is a space or a tab.
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// skipSeparators returns the position of the first character at or
+// after position in input that is not part of a separator between
+// the values of a duration: blanks, commas and the word "and", as in
+// "1 day, 4 hours and 30 minutes".
+func skipSeparators(input string, position int) int {
+	for position < len(input) {
+		switch {
+		case isBlank(input[position]) || input[position] == ',':
+			position++
+		case strings.HasPrefix(input[position:], "and") && position+3 < len(input) && isBlank(input[position+3]):
+			position += 3
+		default:
+			return position
+		}
+	}
+	return position
+}
+
+// scanUnitName returns the unit whose name, from unitNames, starts at
+// position in input, and the position just past it. Names are matched
+// as whole words, so that "2hours30minutes" is read as hours and
+// minutes, and regardless of case, as in "2 Hours", although a
+// single letter must be lower case so that "1M" is not taken for
+// minutes. If no name matches, the symbols comptime accepts are tried
+// as scanUnit does.
+func scanUnitName(input string, position int) (comptime.Unit, int, bool) {
+	end := position
+	for end < len(input) && isLetter(input[end]) {
+		end++
+	}
+	name := input[position:end]
+	if len(name) > 1 {
+		name = strings.ToLower(name)
+	}
+	if unit, ok := unitNames[name]; ok {
+		return unit, end, true
+	}
+
+	return scanUnit(input, position)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decimal is a number written in the extended syntax accepted outside
+// strict mode, whose value is digits × 10^exponent. For example
+// "2_147.5e3" has digits "21475" and exponent 2.
+type decimal struct {
+	digits   string
+	exponent int
+}
+
+// maxExponent bounds the exponent of a non-zero decimal. Any larger
+// value overflows, and any smaller one is finer than a nanosecond,
+// whatever the unit, so there is no need to compute powers of ten
+// beyond it.
+const maxExponent = 40
+
+// scanDecimal scans the number starting at position in input. Besides
+// plain digits, it accepts a decimal fraction, as in "1.5" or ".25",
+// digits grouped with "_" or ",", as in "2_147_483_647" or "1,000",
+// and an exponent, as in "1e3" or "1.5E-3". An underscore may appear
+// between any two digits, but a comma must be followed by exactly
+// three digits, so that a decimal comma such as "1,5" is rejected
+// rather than misread, and is only allowed before the decimal point.
+//
+// It returns the number, the position just past it, and -1, or the
+// position of the first character that makes the number malformed.
+func scanDecimal(input string, position int) (decimal, int, int) {
+	// scanGroup scans digits and the separators between them,
+	// appending the digits to b.
+	var b strings.Builder
+	scanGroup := func(position int, commas bool) (int, int) {
+		start := b.Len()
+		for position < len(input) {
+			c := input[position]
+			switch {
+			case isDigit(c):
+				b.WriteByte(c)
+			case c == '_' && b.Len() > start:
+				if position+1 >= len(input) || !isDigit(input[position+1]) {
+					return position, position
+				}
+			case c == ',' && commas && b.Len() > start:
+				group := position + 1
+				for group < len(input) && isDigit(input[group]) {
+					group++
+				}
+				if group-position-1 != 3 {
+					return position, position
+				}
+			default:
+				return position, -1
+			}
+			position++
+		}
+		return position, -1
+	}
+
+	numStart := position
+	position, bad := scanGroup(position, true)
+	if bad >= 0 {
+		return decimal{}, position, bad
+	}
+	intLen := b.Len()
+
+	if position < len(input) && input[position] == '.' {
+		position++
+		if position >= len(input) || !isDigit(input[position]) {
+			if intLen == 0 {
+				return decimal{}, position, numStart
+			}
+			return decimal{}, position, position
+		}
+		if position, bad = scanGroup(position, false); bad >= 0 {
+			return decimal{}, position, bad
+		}
+	}
+
+	if b.Len() == 0 {
+		return decimal{}, position, numStart
+	}
+	n := decimal{digits: b.String(), exponent: intLen - b.Len()}
+
+	// An "e" is only an exponent if a number follows it;
+	// otherwise it is left to be reported as an invalid unit.
+	if position < len(input) && (input[position] == 'e' || input[position] == 'E') {
+		expStart := position + 1
+		digitStart := expStart
+		if digitStart < len(input) && (input[digitStart] == '+' || input[digitStart] == '-') {
+			digitStart++
+		}
+		end := digitStart
+		for end < len(input) && isDigit(input[end]) {
+			end++
+		}
+		switch {
+		case end > digitStart:
+			exponent, err := strconv.Atoi(input[expStart:end])
+			if err != nil || exponent > 2*maxExponent || exponent < -2*maxExponent {
+				// Only the sign matters for such an
+				// exponent; keep it clear of overflow.
+				exponent = 2 * maxExponent
+				if input[expStart] == '-' {
+					exponent = -exponent
+				}
+			}
+			n.exponent += exponent
+			position = end
+		case digitStart > expStart:
+			return decimal{}, digitStart, digitStart
+		}
+	}
+
+	return n, position, -1
+}
+
+// duration returns the value of n in unit, found at position in the
+// input. An OverflowError is returned if the result cannot be
+// represented, and a SyntaxError with cause inexactFraction if it is
+// not a whole number of microseconds. A value in nanoseconds is
+// instead computed to the nanosecond, truncating any finer fraction
+// as time.ParseDuration does, and left for roundDuration to round.
+func (n decimal) duration(unit time.Duration, position int) (time.Duration, error) {
+	resolution := time.Microsecond
+	truncate := unit < resolution
+	if truncate {
+		resolution = unit
+	}
+
+	digits := strings.TrimLeft(n.digits, "0")
+	if digits == "" {
+		return 0, nil
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exponent := n.exponent + len(digits) - len(trimmed)
+
+	switch {
+	case exponent > maxExponent:
+		return 0, &diagnosticError{class: "OverflowError", position: position}
+	case exponent < -maxExponent-len(trimmed) && truncate:
+		return 0, nil
+	case exponent < -maxExponent-len(trimmed):
+		return 0, &diagnosticError{class: "SyntaxError", cause: inexactFraction, position: position}
+	}
+
+	count, _ := new(big.Int).SetString(trimmed, 10)
+	count.Mul(count, big.NewInt(int64(unit/resolution)))
+
+	if exponent >= 0 {
+		count.Mul(count, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+	} else {
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exponent)), nil)
+		var remainder big.Int
+		if count.QuoRem(count, divisor, &remainder); remainder.Sign() != 0 && !truncate {
+			return 0, &diagnosticError{class: "SyntaxError", cause: inexactFraction, position: position}
+		}
+	}
+
+	if !count.IsInt64() || count.Int64() > math.MaxInt64/int64(resolution) {
+		return 0, &diagnosticError{class: "OverflowError", position: position}
+	}
+
+	return time.Duration(count.Int64()) * resolution, nil
+}
+
+// rat returns the exact value of n.
+func (n decimal) rat() *big.Rat {
+	value, _ := new(big.Rat).SetString(n.digits)
+	if n.exponent < 0 {
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-n.exponent)), nil)
+		return value.Quo(value, new(big.Rat).SetInt(scale))
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n.exponent)), nil)
+	return value.Mul(value, new(big.Rat).SetInt(scale))
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Proxy section kinds that can carry timeouts.
+const (
+	sectionDefaults = "defaults"
+	sectionFrontend = "frontend"
+	sectionBackend  = "backend"
+	sectionListen   = "listen"
+)
+
+// sectionKeywords lists every keyword that begins a new section in an
+// HAProxy configuration. Lines following a section that is not a
+// proxy section are ignored by the resolver.
+var sectionKeywords = map[string]bool{
+	"backend":     true,
+	"cache":       true,
+	"crt-store":   true,
+	"defaults":    true,
+	"frontend":    true,
+	"global":      true,
+	"http-errors": true,
+	"listen":      true,
+	"log-forward": true,
+	"mailers":     true,
+	"peers":       true,
+	"program":     true,
+	"resolvers":   true,
+	"ring":        true,
+	"traces":      true,
+	"userlist":    true,
+}
+
+// timeoutSections records, for each argument of the "timeout"
+// keyword, the proxy kinds in which HAProxy honours it. A frontend
+// never uses server-side timeouts and a backend never uses client-side
+// ones, even when they are inherited from a defaults section.
+var timeoutSections = map[string][]string{
+	"check":           {sectionBackend, sectionListen},
+	"client":          {sectionFrontend, sectionListen},
+	"client-fin":      {sectionFrontend, sectionListen},
+	"client-hs":       {sectionFrontend, sectionListen},
+	"connect":         {sectionBackend, sectionListen},
+	"http-keep-alive": {sectionFrontend, sectionBackend, sectionListen},
+	"http-request":    {sectionFrontend, sectionBackend, sectionListen},
+	"queue":           {sectionBackend, sectionListen},
+	"server":          {sectionBackend, sectionListen},
+	"server-fin":      {sectionBackend, sectionListen},
+	"tarpit":          {sectionFrontend, sectionBackend, sectionListen},
+	"tunnel":          {sectionBackend, sectionListen},
+}
+
+// timeoutSetting is the value of a timeout together with where it
+// was set.
+type timeoutSetting struct {
+	value    time.Duration
+	filename string
+	line     int
+
+	// section describes the section containing the directive,
+	// for example "defaults web".
+	section string
+}
+
+// proxySection is a defaults, frontend, backend or listen section
+// with the timeouts in force in it, whether set locally or
+// inherited.
+type proxySection struct {
+	kind     string
+	name     string
+	filename string
+	line     int
+	timeouts map[string]timeoutSetting
+}
+
+// String returns the section keyword followed by its name, if any.
+func (p *proxySection) String() string {
+	if p.name == "" {
+		return p.kind
+	}
+	return p.kind + " " + p.name
+}
+
+// configFile is the name and contents of a configuration file.
+type configFile struct {
+	name string
+	data string
+}
+
+// timeoutResolver tracks the state needed to compute the timeouts in
+// force in each proxy as configuration files are read in order.
+type timeoutResolver struct {
+	w           io.Writer
+	exitHandler ExitHandler
+
+	// proxies are the frontend, backend and listen sections in
+	// the order they were declared.
+	proxies []*proxySection
+
+	// named maps the name of each named defaults section to the
+	// section.
+	named map[string]*proxySection
+
+	// lastDefaults is the most recent defaults section, named or
+	// not, which proxies without a "from" clause inherit from.
+	lastDefaults *proxySection
+
+	// problems counts the errors reported while resolving.
+	problems int
+}
+
+// startSection handles a line that opens a proxy section of the given
+// kind: "defaults [<name>] [from <defaults>]" or "<kind> <name> [from
+// <defaults>]". A defaults section without a "from" clause starts
+// with no timeouts; any other proxy starts with the timeouts of the
+// defaults section it names, or of the most recent defaults section.
+func (r *timeoutResolver) startSection(filename string, line configLine) *proxySection {
+	words := line.words()
+	section := &proxySection{
+		kind:     words[0],
+		filename: filename,
+		line:     line.number,
+		timeouts: map[string]timeoutSetting{},
+	}
+
+	args := words[1:]
+	if len(args) > 0 && args[0] != "from" {
+		section.name = args[0]
+		args = args[1:]
+	}
+
+	parent := r.lastDefaults
+	if section.kind == sectionDefaults {
+		parent = nil
+	}
+
+	if len(args) >= 2 && args[0] == "from" {
+		var ok bool
+		if parent, ok = r.named[args[1]]; !ok {
+			tv := timeValue{directive: &directive{name: section.String()}, token: line.tokens[len(line.tokens)-1]}
+			printConfigError(r.w, r.exitHandler, filename, line, tv, fmt.Errorf("unknown defaults section %q", args[1]))
+			r.problems++
+		}
+	}
+
+	if parent != nil {
+		for name, setting := range parent.timeouts {
+			section.timeouts[name] = setting
+		}
+	}
+
+	if section.kind == sectionDefaults {
+		r.lastDefaults = section
+		if section.name != "" {
+			r.named[section.name] = section
+		}
+	} else {
+		r.proxies = append(r.proxies, section)
+	}
+
+	return section
+}
+
+// resolveFile reads one configuration file, updating the resolver's
+// sections. Files are processed in order, as HAProxy does when given
+// several -f options, so sections may inherit from defaults declared
+// in an earlier file.
+func (r *timeoutResolver) resolveFile(filename, data string) {
+	var current *proxySection
+
+	for _, line := range parseConfig(data) {
+		if len(line.tokens) == 0 {
+			continue
+		}
+
+		if keyword := line.tokens[0].text; sectionKeywords[keyword] {
+			current = nil
+			switch keyword {
+			case sectionDefaults, sectionFrontend, sectionBackend, sectionListen:
+				current = r.startSection(filename, line)
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		for _, tv := range findTimeValues(line) {
+			name := strings.TrimPrefix(tv.directive.name, "timeout ")
+			if _, ok := timeoutSections[name]; !ok {
+				continue
+			}
+
+			duration, ok := checkTimeValue(r.w, r.exitHandler, filename, line, tv, parseOptions{plain: true})
+			if !ok {
+				r.problems++
+				continue
+			}
+
+			current.timeouts[name] = timeoutSetting{
+				value:    duration,
+				filename: filename,
+				line:     line.number,
+				section:  current.String(),
+			}
+		}
+	}
+}
+
+// resolveTimeouts computes the effective timeouts of every frontend,
+// backend and listen section in files, reporting invalid values and
+// unknown defaults sections to w. It returns the proxies in
+// declaration order and the number of problems found.
+func resolveTimeouts(w io.Writer, exitHandler ExitHandler, files []configFile) ([]*proxySection, int) {
+	r := &timeoutResolver{
+		w:           w,
+		exitHandler: exitHandler,
+		named:       map[string]*proxySection{},
+	}
+
+	for _, file := range files {
+		r.resolveFile(file.name, file.data)
+	}
+
+	return r.proxies, r.problems
+}
+
+// printProxyTimeouts writes a table to w of the timeouts in force in
+// each proxy that apply to that kind of proxy, formatted with
+// formatDuration, along with the file, line and section where each
+// value was set.
+func printProxyTimeouts(w io.Writer, exitHandler ExitHandler, proxies []*proxySection) {
+	var b strings.Builder
+
+	for i, proxy := range proxies {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s (%s:%d)\n", proxy, proxy.filename, proxy.line)
+
+		var names []string
+		for name := range proxy.timeouts {
+			for _, kind := range timeoutSections[name] {
+				if kind == proxy.kind {
+					names = append(names, name)
+				}
+			}
+		}
+		sort.Strings(names)
+
+		tw := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+		for _, name := range names {
+			setting := proxy.timeouts[name]
+			fmt.Fprintf(tw, "    timeout %s\t%s\t%s:%d\t%s\n", name, formatDuration(setting.value), setting.filename, setting.line, setting.section)
+		}
+		_ = tw.Flush()
+	}
+
+	safeFprintf(w, exitHandler, "%s", b.String())
+}
+
+// resolveCommand implements "haproxytime resolve [<file>...]". The
+// named HAProxy configuration files, or stdin if none is given, are
+// read in order and the effective timeouts of every frontend, backend
+// and listen section are printed, following defaults inheritance
+// including HAProxy 2.4+ named defaults sections and "from" clauses.
+// Values are read with parseOptions.plain, as HAProxy loads none of
+// the other extensions. It returns exitSuccess on success, exitFailure if any problem was
+// found and exitUsage for invalid flags.
+func resolveCommand(rdr io.Reader, stdout, stderr io.Writer, args []string, exitHandler ExitHandler) int {
+	fs := flag.NewFlagSet("haproxytime resolve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	if err := fs.Parse(args); err != nil {
+		safeFprintln(stderr, exitHandler, err)
+		return exitUsage
+	}
+
+	filenames := fs.Args()
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+
+	status := exitSuccess
+
+	var files []configFile
+	for _, filename := range filenames {
+		data, err := readConfig(rdr, filename)
+		if err != nil {
+			safeFprintln(stderr, exitHandler, err)
+			status = exitFailure
+			continue
+		}
+
+		name := configName(filename)
+		files = append(files, configFile{name: name, data: data})
+	}
+
+	proxies, problems := resolveTimeouts(stderr, exitHandler, files)
+	if problems > 0 {
+		status = exitFailure
+	}
+
+	printProxyTimeouts(stdout, exitHandler, proxies)
+	return status
+}
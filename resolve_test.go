@@ -0,0 +1,163 @@
+package main_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	cmd "github.com/frobware/haproxytime"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		description    string
+		config         string
+		expectedExit   int
+		expectedStdout string
+		expectedStderr string
+	}{{
+		description: "inheritance from the most recent defaults section",
+		config: `
+defaults
+    timeout client 30s
+    timeout server 1m
+    timeout connect 5s
+
+frontend fe
+    timeout client 1m30s
+
+backend be
+    timeout server 2m
+`[1:],
+		expectedExit: 0,
+		expectedStdout: `
+frontend fe (<stdin>:6)
+    timeout client  1m30s  <stdin>:7  frontend fe
+
+backend be (<stdin>:9)
+    timeout connect  5s  <stdin>:4   defaults
+    timeout server   2m  <stdin>:10  backend be
+`[1:],
+	}, {
+		description: "a new defaults section resets earlier values",
+		config: `
+defaults
+    timeout server 1m
+    timeout connect 5s
+
+defaults
+    timeout server 2m
+
+listen li
+    timeout client 1s
+`[1:],
+		expectedExit: 0,
+		expectedStdout: `
+listen li (<stdin>:8)
+    timeout client  1s  <stdin>:9  listen li
+    timeout server  2m  <stdin>:6  defaults
+`[1:],
+	}, {
+		description: "named defaults and from clauses",
+		config: `
+defaults base
+    timeout connect 5s
+    timeout server 1m
+
+defaults web from base
+    timeout server 5m
+    timeout http-request 10s
+
+defaults other
+    timeout server 1s
+
+backend be from web
+    timeout queue 30s
+
+backend api from base
+`[1:],
+		expectedExit: 0,
+		expectedStdout: `
+backend be (<stdin>:12)
+    timeout connect       5s   <stdin>:2   defaults base
+    timeout http-request  10s  <stdin>:7   defaults web
+    timeout queue         30s  <stdin>:13  backend be
+    timeout server        5m   <stdin>:6   defaults web
+
+backend api (<stdin>:15)
+    timeout connect  5s  <stdin>:2  defaults base
+    timeout server   1m  <stdin>:3  defaults base
+`[1:],
+	}, {
+		description: "timeouts in non-proxy sections are ignored",
+		config: `
+resolvers dns
+    timeout resolve 1s
+
+frontend fe
+`[1:],
+		expectedExit: 0,
+		expectedStdout: `
+frontend fe (<stdin>:4)
+`[1:],
+	}, {
+		description: "unknown defaults section",
+		config: `
+backend be from missing
+    timeout server 1s
+`[1:],
+		expectedExit: 1,
+		expectedStdout: `
+backend be (<stdin>:1)
+    timeout server  1s  <stdin>:2  backend be
+`[1:],
+		expectedStderr: "<stdin>:1:17: backend be: unknown defaults section \"missing\"\nbackend be from missing\n                ^~~~~~~\n",
+	}, {
+		description: "invalid values are reported",
+		config: `
+defaults
+    timeout server 30d
+
+backend be
+`[1:],
+		expectedExit: 1,
+		expectedStdout: `
+backend be (<stdin>:4)
+`[1:],
+		expectedStderr: "<stdin>:2:20: timeout server: range error at position 1\n    timeout server 30d\n                   ^~~\n",
+	}, {
+		description: "values HAProxy cannot load are reported",
+		config: `
+defaults
+    timeout server 1.5h
+
+backend be
+`[1:],
+		expectedExit: 1,
+		expectedStdout: `
+backend be (<stdin>:4)
+`[1:],
+		expectedStderr: "<stdin>:2:21: timeout server: syntax error at position 2: invalid unit\n    timeout server 1.5h\n                    ^\n",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+
+			exitCode := cmd.ConvertDuration(strings.NewReader(tc.config), stdout, stderr, []string{"resolve"}, &mockExitHandler{})
+
+			if exitCode != tc.expectedExit {
+				t.Errorf("Expected exit code %d, but got %d", tc.expectedExit, exitCode)
+			}
+
+			if stdout.String() != tc.expectedStdout {
+				t.Errorf("Expected stdout:\n<<<%s>>>\nBut got:\n<<<%s>>>", tc.expectedStdout, stdout.String())
+			}
+
+			if stderr.String() != tc.expectedStderr {
+				t.Errorf("Expected stderr:\n<<<%s>>>\nBut got:\n<<<%s>>>", tc.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
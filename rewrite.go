@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// rewriteConfig returns data with every time value replaced by its
+// canonical form: milliseconds with formatMs, or the largest exact
+// single unit with formatUnit. Everything else, including comments,
+// indentation, line endings and the order of directives, is preserved
+// byte for byte. A quoted value is replaced along with its quotes
+// since neither form ever needs quoting. Values that are not a whole
+// number of milliseconds are rounded using opts. Values that cannot
+// be converted are reported to w in the same format as lintConfig and
+// left untouched. It returns the rewritten data and the number of
+// problems found.
+func rewriteConfig(w io.Writer, exitHandler ExitHandler, filename, data string, opts parseOptions, format outputFormat) (string, int) {
+	problems := 0
+	rawLines := strings.Split(data, "\n")
+
+	for _, line := range parseConfig(data) {
+		raw := rawLines[line.number-1]
+		values := findTimeValues(line)
+
+		// Replace from the end of the line so that the
+		// offsets of earlier tokens remain valid.
+		for i := len(values) - 1; i >= 0; i-- {
+			tv := values[i]
+			duration, ok := checkTimeValue(w, exitHandler, filename, line, tv, opts)
+			if !ok {
+				problems++
+				continue
+			}
+
+			raw = raw[:tv.token.start] + format.format(duration) + raw[tv.token.end:]
+		}
+
+		rawLines[line.number-1] = raw
+	}
+
+	return strings.Join(rawLines, "\n"), problems
+}
+
+// unifiedDiff returns a unified diff, with three lines of context,
+// between oldData and newData, labelling both sides with filename.
+// It relies on rewriteConfig only ever substituting text within a
+// line: both inputs must contain the same number of lines. An empty
+// string is returned if the inputs are identical.
+func unifiedDiff(filename, oldData, newData string) string {
+	const context = 3
+
+	oldLines := strings.Split(oldData, "\n")
+	newLines := strings.Split(newData, "\n")
+
+	var changed []int
+	for i := range oldLines {
+		if oldLines[i] != newLines[i] {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	// A trailing newline leaves an empty final element that is
+	// not a line in its own right.
+	lineCount := len(oldLines)
+	if oldLines[lineCount-1] == "" {
+		lineCount--
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", filename, filename)
+
+	for i := 0; i < len(changed); {
+		// Grow the hunk while the next change falls within
+		// the context of the previous one.
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*context {
+			j++
+		}
+
+		start := changed[i] - context
+		if start < 0 {
+			start = 0
+		}
+		end := changed[j] + context + 1
+		if end > lineCount {
+			end = lineCount
+		}
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+
+		// Emit removed lines before added lines within each
+		// run of consecutive changes, as diff(1) does.
+		for n := start; n < end; {
+			if oldLines[n] == newLines[n] {
+				fmt.Fprintf(&b, " %s\n", oldLines[n])
+				n++
+				continue
+			}
+			m := n
+			for m < end && oldLines[m] != newLines[m] {
+				m++
+			}
+			for k := n; k < m; k++ {
+				fmt.Fprintf(&b, "-%s\n", oldLines[k])
+			}
+			for k := n; k < m; k++ {
+				fmt.Fprintf(&b, "+%s\n", newLines[k])
+			}
+			n = m
+		}
+
+		i = j + 1
+	}
+
+	return b.String()
+}
+
+// writeConfig replaces the contents of filename with data, keeping
+// the file's permissions. If backupSuffix is not empty the original
+// contents, oldData, are first saved to filename+backupSuffix.
+func writeConfig(filename, oldData, data, backupSuffix string) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("error writing: %w", err)
+	}
+
+	if backupSuffix != "" {
+		if err := os.WriteFile(filename+backupSuffix, []byte(oldData), info.Mode().Perm()); err != nil {
+			return fmt.Errorf("error writing backup: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(filename, []byte(data), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing: %w", err)
+	}
+
+	return nil
+}
+
+// rewriteCommand implements "haproxytime rewrite [-diff] [-backup
+// <suffix>] [-round <mode>] [-o <format>] [<file>...]". Every time
+// value in each named HAProxy configuration file is converted to
+// milliseconds, or with "-o unit" to the largest exact single unit,
+// and the file is rewritten in place, after saving the original with
+// the backup suffix. With -diff the files are left alone and a
+// unified diff of the changes is written to stdout instead. If no
+// file, or "-", is given the configuration is read from stdin and the
+// result written to stdout. A file containing values that cannot be
+// converted is never modified. It returns exitSuccess on success,
+// exitFailure if any problem was found and exitUsage for invalid
+// flags.
+func rewriteCommand(rdr io.Reader, stdout, stderr io.Writer, args []string, exitHandler ExitHandler) int {
+	fs := flag.NewFlagSet("haproxytime rewrite", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var printDiff bool
+	var backupSuffix string
+	var opts parseOptions
+	var format outputFormat
+
+	fs.BoolVar(&printDiff, "diff", false, "Print a unified diff instead of rewriting files")
+	fs.Var(&format, "o", "Output format: ms or unit")
+	fs.Var(&opts.rounding, "round", "Rounding mode for sub-millisecond values: down, up, nearest or error")
+	fs.StringVar(&backupSuffix, "backup", ".bak", "Suffix of the backup file; empty disables backups")
+
+	if err := fs.Parse(args); err != nil {
+		safeFprintln(stderr, exitHandler, err)
+		return exitUsage
+	}
+
+	if format != formatMs && format != formatUnit {
+		safeFprintf(stderr, exitHandler, "rewrite: -o %s is not valid HAProxy syntax; use ms or unit\n", format.String())
+		return exitUsage
+	}
+
+	filenames := fs.Args()
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+
+	status := exitSuccess
+
+	for _, filename := range filenames {
+		data, err := readConfig(rdr, filename)
+		if err != nil {
+			safeFprintln(stderr, exitHandler, err)
+			status = exitFailure
+			continue
+		}
+
+		name := configName(filename)
+		rewritten, problems := rewriteConfig(stderr, exitHandler, name, data, opts, format)
+		if problems > 0 {
+			status = exitFailure
+			continue
+		}
+
+		switch {
+		case printDiff:
+			safeFprintf(stdout, exitHandler, "%s", unifiedDiff(name, data, rewritten))
+		case filename == "-":
+			safeFprintf(stdout, exitHandler, "%s", rewritten)
+		case rewritten != data:
+			if err := writeConfig(filename, data, rewritten, backupSuffix); err != nil {
+				safeFprintln(stderr, exitHandler, err)
+				status = exitFailure
+			}
+		}
+	}
+
+	return status
+}
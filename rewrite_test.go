@@ -0,0 +1,213 @@
+package main_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/frobware/haproxytime"
+)
+
+const sourceConfig = `
+# Source configuration.
+defaults
+    timeout client 2h30m   # long lived
+    timeout server "1m30s"
+	timeout connect 5s
+
+frontend fe
+    bind :80
+    maxconn 100
+    option httplog
+    option forwardfor
+    log global
+    timeout http-request 10s
+`
+
+const renderedConfig = `
+# Source configuration.
+defaults
+    timeout client 9000000ms   # long lived
+    timeout server 90000ms
+	timeout connect 5000ms
+
+frontend fe
+    bind :80
+    maxconn 100
+    option httplog
+    option forwardfor
+    log global
+    timeout http-request 10000ms
+`
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		config         string
+		expectedExit   int
+		expectedStdout string
+		expectedStderr string
+	}{{
+		description:    "rewrite stdin to stdout",
+		args:           []string{"rewrite"},
+		config:         sourceConfig,
+		expectedExit:   0,
+		expectedStdout: renderedConfig,
+	}, {
+		description:    "CRLF line endings are preserved",
+		args:           []string{"rewrite", "-"},
+		config:         "timeout client 1s\r\ntimeout server 2s\r\n",
+		expectedExit:   0,
+		expectedStdout: "timeout client 1000ms\r\ntimeout server 2000ms\r\n",
+	}, {
+		description:  "unified diff",
+		args:         []string{"rewrite", "-diff"},
+		config:       sourceConfig,
+		expectedExit: 0,
+		expectedStdout: `
+--- <stdin>
++++ <stdin>
+@@ -1,9 +1,9 @@
+ 
+ # Source configuration.
+ defaults
+-    timeout client 2h30m   # long lived
+-    timeout server "1m30s"
+-	timeout connect 5s
++    timeout client 9000000ms   # long lived
++    timeout server 90000ms
++	timeout connect 5000ms
+ 
+ frontend fe
+     bind :80
+@@ -11,4 +11,4 @@
+     option httplog
+     option forwardfor
+     log global
+-    timeout http-request 10s
++    timeout http-request 10000ms
+`[1:],
+	}, {
+		description:    "unified diff with no changes",
+		args:           []string{"rewrite", "-diff"},
+		config:         renderedConfig,
+		expectedExit:   0,
+		expectedStdout: "",
+	}, {
+		description:    "sub-millisecond values are rounded",
+		args:           []string{"rewrite", "-round", "up"},
+		config:         "timeout client 1500us\n",
+		expectedExit:   0,
+		expectedStdout: "timeout client 2ms\n",
+		expectedStderr: "<stdin>:1:16: timeout client: warning: 1500us is not a whole number of milliseconds; rounded up to 2ms\ntimeout client 1500us\n               ^~~~~~\n",
+	}, {
+		description:    "directives stored in seconds are rounded to whole seconds",
+		args:           []string{"rewrite"},
+		config:         "stats refresh 2.5\n",
+		expectedExit:   0,
+		expectedStdout: "stats refresh 2000ms\n",
+		expectedStderr: "<stdin>:1:15: stats refresh: warning: 2.5 is not a whole number of seconds; rounded down to 2000ms\nstats refresh 2.5\n              ^~~\n",
+	}, {
+		description:    "rewrite to single units",
+		args:           []string{"rewrite", "-o", "unit"},
+		config:         "timeout client 1h30m\ntimeout server 1500\ntimeout connect 2d\n",
+		expectedExit:   0,
+		expectedStdout: "timeout client 90m\ntimeout server 1500ms\ntimeout connect 2d\n",
+	}, {
+		description:    "human output is not valid HAProxy syntax",
+		args:           []string{"rewrite", "-o", "human"},
+		config:         "timeout client 1h30m\n",
+		expectedExit:   2,
+		expectedStderr: "rewrite: -o human is not valid HAProxy syntax; use ms or unit\n",
+	}, {
+		description:    "json output is not valid HAProxy syntax",
+		args:           []string{"rewrite", "-o", "json"},
+		config:         "timeout client 1h30m\n",
+		expectedExit:   2,
+		expectedStderr: "rewrite: -o json is not valid HAProxy syntax; use ms or unit\n",
+	}, {
+		description:    "invalid values are reported and nothing is written",
+		args:           []string{"rewrite"},
+		config:         "defaults\n    timeout client 1s\n    timeout server 30d\n",
+		expectedExit:   1,
+		expectedStdout: "",
+		expectedStderr: "<stdin>:3:20: timeout server: range error at position 1\n    timeout server 30d\n                   ^~~\n",
+	}, {
+		description:    "blank and comma-only values are not rewritten to 0ms",
+		args:           []string{"rewrite", "-diff"},
+		config:         "timeout server \" \"\ntimeout connect \",\"\n",
+		expectedExit:   1,
+		expectedStdout: "",
+		expectedStderr: "<stdin>:1:17: timeout server: syntax error at position 1: invalid number\ntimeout server \" \"\n                ^\n<stdin>:2:18: timeout connect: syntax error at position 1: invalid number\ntimeout connect \",\"\n                 ^\n",
+	}, {
+		description:    "lines with words after the value are not rewritten",
+		args:           []string{"rewrite"},
+		config:         "timeout server 2h - 15m\n",
+		expectedExit:   1,
+		expectedStdout: "",
+		expectedStderr: "<stdin>:1:19: timeout server: unexpected argument \"-\" after the time value\ntimeout server 2h - 15m\n                  ^\n",
+	}, {
+		description:    "empty quoted values are not rewritten to 0ms",
+		args:           []string{"rewrite", "-diff"},
+		config:         "timeout client \"\"\n",
+		expectedExit:   1,
+		expectedStdout: "",
+		expectedStderr: "<stdin>:1:16: timeout client: missing time value\ntimeout client \"\"\n               ^\n",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+
+			exitCode := cmd.ConvertDuration(strings.NewReader(tc.config), stdout, stderr, tc.args, &mockExitHandler{})
+
+			if exitCode != tc.expectedExit {
+				t.Errorf("Expected exit code %d, but got %d", tc.expectedExit, exitCode)
+			}
+
+			if stdout.String() != tc.expectedStdout {
+				t.Errorf("Expected stdout:\n<<<%s>>>\nBut got:\n<<<%s>>>", tc.expectedStdout, stdout.String())
+			}
+
+			if stderr.String() != tc.expectedStderr {
+				t.Errorf("Expected stderr:\n<<<%s>>>\nBut got:\n<<<%s>>>", tc.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRewriteFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "haproxy.cfg")
+
+	if err := os.WriteFile(filename, []byte(sourceConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := &bytes.Buffer{}
+	if exitCode := cmd.ConvertDuration(nil, &bytes.Buffer{}, stderr, []string{"rewrite", filename}, &mockExitHandler{}); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, but got %d: %s", exitCode, stderr.String())
+	}
+
+	for name, expected := range map[string]string{filename: renderedConfig, filename + ".bak": sourceConfig} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("Expected %s:\n<<<%s>>>\nBut got:\n<<<%s>>>", name, expected, data)
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// roundingMode selects how a duration with a sub-millisecond
+// component is reduced to whole milliseconds, the finest resolution
+// HAProxy supports, or to whole seconds for directives that HAProxy
+// stores in seconds.
+type roundingMode int
+
+const (
+	// roundDown truncates towards zero, as HAProxy does.
+	roundDown roundingMode = iota
+
+	// roundUp rounds towards the next whole millisecond.
+	roundUp
+
+	// roundNearest rounds to the nearest whole millisecond,
+	// with halfway values rounded up.
+	roundNearest
+
+	// roundError rejects any value that is not a whole number of
+	// milliseconds.
+	roundError
+)
+
+// roundingModes maps the names accepted by the -round flag to their
+// rounding modes.
+var roundingModes = map[string]roundingMode{
+	"down":    roundDown,
+	"up":      roundUp,
+	"nearest": roundNearest,
+	"error":   roundError,
+}
+
+// String returns the name of the rounding mode as accepted by the
+// -round flag.
+func (m *roundingMode) String() string {
+	return flagName(roundingModes, *m)
+}
+
+// Set implements flag.Value, allowing a rounding mode to be used
+// directly as a flag.
+func (m *roundingMode) Set(s string) error {
+	return setFlag(roundingModes, s, m)
+}
+
+// resolutionName returns the plural name and symbol of the unit in
+// which HAProxy stores a value with the given resolution.
+func resolutionName(resolution time.Duration) (string, string) {
+	if resolution == time.Second {
+		return "seconds", "s"
+	}
+	return "milliseconds", "ms"
+}
+
+// precisionWarning is returned by roundDuration when a value has been
+// rounded to the resolution of its directive. It is not fatal: the
+// rounded value is returned alongside it and callers are expected to
+// report the warning and carry on.
+type precisionWarning struct {
+	input      string
+	rounded    time.Duration
+	resolution time.Duration
+
+	// up is true if the value was rounded away from zero.
+	up bool
+}
+
+// Error returns a message describing the rounding that took place.
+func (e *precisionWarning) Error() string {
+	direction := "down"
+	if e.up {
+		direction = "up"
+	}
+	name, _ := resolutionName(e.resolution)
+	return fmt.Sprintf("warning: %s is not a whole number of %s; rounded %s to %s", e.input, name, direction, formatMilliseconds(e.rounded))
+}
+
+// Position returns the 0-based position of the warning in the input,
+// which is always 0 as it applies to the value as a whole.
+func (e *precisionWarning) Position() int {
+	return 0
+}
+
+// precisionError reports a value that is not a whole number of the
+// resolution of its directive when the rounding mode is roundError.
+type precisionError struct {
+	input      string
+	resolution time.Duration
+}
+
+// Error returns a message naming the offending value.
+func (e *precisionError) Error() string {
+	name, _ := resolutionName(e.resolution)
+	return fmt.Sprintf("precision error: %s is not a whole number of %s", e.input, name)
+}
+
+// Position returns the 0-based position of the error in the input,
+// which is always 0 as it applies to the value as a whole.
+func (e *precisionError) Position() int {
+	return 0
+}
+
+// underflowError reports a non-zero value that rounds to zero at the
+// resolution of its directive. HAProxy treats a zero timeout as no
+// timeout at all, the opposite of what a very short value intends,
+// and rejects such values in its own configuration as a timer
+// underflow.
+type underflowError struct {
+	input      string
+	resolution time.Duration
+}
+
+// Error returns a message naming the offending value.
+func (e *underflowError) Error() string {
+	_, symbol := resolutionName(e.resolution)
+	return fmt.Sprintf("underflow error: %s rounds to 0%s, which HAProxy treats as no timeout", e.input, symbol)
+}
+
+// Position returns the 0-based position of the error in the input,
+// which is always 0 as it applies to the value as a whole.
+func (e *underflowError) Position() int {
+	return 0
+}
+
+// roundDuration rounds duration, parsed from input, to a whole
+// multiple of resolution, the precision with which HAProxy stores the
+// value, using mode. A zero resolution means milliseconds. A value
+// that is already a whole multiple is returned unchanged with a nil
+// error. Otherwise:
+//
+//   - with roundError, a *precisionError is returned;
+//   - if a non-zero value rounds to zero, an *underflowError is
+//     returned;
+//   - else the rounded value is returned with a *precisionWarning.
+func roundDuration(input string, duration time.Duration, mode roundingMode, resolution time.Duration) (time.Duration, error) {
+	if resolution == 0 {
+		resolution = time.Millisecond
+	}
+
+	remainder := duration % resolution
+	if remainder == 0 {
+		return duration, nil
+	}
+
+	if mode == roundError {
+		return 0, &precisionError{input: input, resolution: resolution}
+	}
+
+	up := mode == roundUp || mode == roundNearest && remainder >= resolution/2
+
+	rounded := duration - remainder
+	if up {
+		rounded += resolution
+	}
+
+	if rounded == 0 {
+		return 0, &underflowError{input: input, resolution: resolution}
+	}
+
+	return rounded, &precisionWarning{input: input, rounded: rounded, resolution: resolution, up: up}
+}
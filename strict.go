@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/frobware/comptime"
+)
+
+// emptyValueError reports an empty value in strict mode, or a blank
+// line in batch mode. HAProxy requires every time value to start with
+// a digit.
+type emptyValueError struct{}
+
+// Error returns a message describing the error.
+func (e *emptyValueError) Error() string {
+	return "syntax error at position 1: empty value"
+}
+
+// Position returns the 0-based position of the error in the input,
+// which is always 0.
+func (e *emptyValueError) Position() int {
+	return 0
+}
+
+// haproxyMessage returns the message HAProxy prints when it rejects
+// input as the value of directive d, given the error returned by
+// convertValue in strict mode. An empty string is returned for errors
+// that HAProxy has no equivalent for.
+func haproxyMessage(input string, d *directive, err error) string {
+	// HAProxy stops at the first problem.
+	err = firstError(err)
+
+	name := d.name
+	if name == "" {
+		name = "timeout"
+	}
+
+	var emptyErr *emptyValueError
+	var syntaxErr *comptime.SyntaxError
+	var overflowErr *comptime.OverflowError
+	var rangeErr *comptime.RangeError
+	var underflowErr *underflowError
+
+	switch {
+	case errors.As(err, &emptyErr):
+		return fmt.Sprintf("'%s' expects an integer value", name)
+	case errors.As(err, &syntaxErr):
+		if pos := syntaxErr.Position(); pos < len(input) {
+			r, _ := utf8.DecodeRuneInString(input[pos:])
+			return fmt.Sprintf("unexpected character '%c' in '%s'", r, name)
+		}
+		return fmt.Sprintf("'%s' expects an integer value", name)
+	case errors.As(err, &overflowErr), errors.As(err, &rangeErr):
+		return fmt.Sprintf("timer overflow in argument '%s' to '%s' (maximum value is %d ms)", input, name, d.max.Milliseconds())
+	case errors.As(err, &underflowErr):
+		_, symbol := resolutionName(underflowErr.resolution)
+		return fmt.Sprintf("timer underflow in argument '%s' to '%s' (minimum non-null value is 1 %s)", input, name, symbol)
+	}
+
+	return ""
+}
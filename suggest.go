@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// unitAlias describes how a commonly used but invalid unit is written
+// in HAProxy's syntax: value*multiply/divide of unit.
+type unitAlias struct {
+	unit             string
+	multiply, divide int64
+}
+
+// unitAliases maps the unit symbols recognised when suggesting a
+// correction, both HAProxy's own and common near-misses, to the
+// HAProxy unit they stand for.
+var unitAliases = map[string]unitAlias{
+	"d":     {"d", 1, 1},
+	"h":     {"h", 1, 1},
+	"m":     {"m", 1, 1},
+	"s":     {"s", 1, 1},
+	"ms":    {"ms", 1, 1},
+	"us":    {"us", 1, 1},
+	"w":     {"d", 7, 1},
+	"hr":    {"h", 1, 1},
+	"hrs":   {"h", 1, 1},
+	"min":   {"m", 1, 1},
+	"mins":  {"m", 1, 1},
+	"sec":   {"s", 1, 1},
+	"secs":  {"s", 1, 1},
+	"msec":  {"ms", 1, 1},
+	"msecs": {"ms", 1, 1},
+	"µs":    {"us", 1, 1}, // U+00B5 MICRO SIGN
+	"μs":    {"us", 1, 1}, // U+03BC GREEK SMALL LETTER MU
+	"ns":    {"us", 1, 1000},
+}
+
+// suggestUnits returns input rewritten with every unit replaced by
+// its HAProxy equivalent from unitAliases, for example "7d12h" for
+// "1w12hrs", or an empty string if input uses no such unit.
+// Weeks are written as days and nanoseconds as microseconds, provided
+// the value is a whole number of microseconds. A suggestion is only
+// made if it differs from input and converts using opts without
+// error.
+func suggestUnits(input string, opts parseOptions) string {
+	type segment struct {
+		value int64
+		unit  string
+	}
+
+	var segments []segment
+	changed := false
+
+	for position := 0; position < len(input); {
+		numStart := position
+		for position < len(input) && input[position] >= '0' && input[position] <= '9' {
+			position++
+		}
+		value, err := strconv.ParseInt(input[numStart:position], 10, 64)
+		if err != nil {
+			return ""
+		}
+
+		unitStart := position
+		for position < len(input) && (input[position] < '0' || input[position] > '9') {
+			position++
+		}
+		symbol := strings.TrimSpace(input[unitStart:position])
+
+		unit := ""
+		if symbol != "" {
+			alias, ok := unitAliases[symbol]
+			if !ok || value%alias.divide != 0 || value > math.MaxInt64/alias.multiply {
+				return ""
+			}
+			value = value * alias.multiply / alias.divide
+			unit = alias.unit
+			changed = changed || symbol != unit
+		}
+
+		// Merge values that end up in the same unit, such as
+		// "1w2d", which would otherwise be out of order.
+		if n := len(segments); n > 0 && segments[n-1].unit == unit && unit != "" {
+			if segments[n-1].value > math.MaxInt64-value {
+				return ""
+			}
+			segments[n-1].value += value
+			continue
+		}
+
+		segments = append(segments, segment{value: value, unit: unit})
+	}
+
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteString(strconv.FormatInt(s.value, 10))
+		b.WriteString(s.unit)
+	}
+
+	// Values that are only out of order are left to
+	// reorderedForm.
+	suggestion := b.String()
+	if !changed || suggestion == input {
+		return ""
+	}
+
+	var warning *precisionWarning
+	if _, err := convertValue(suggestion, opts); err != nil && !errors.As(err, &warning) {
+		return ""
+	}
+
+	return suggestion
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// inputSyntax selects the grammar durations are read with.
+type inputSyntax int
+
+const (
+	// syntaxHAProxy reads durations in HAProxy's composite
+	// syntax, with the extensions parseDuration allows.
+	syntaxHAProxy inputSyntax = iota
+
+	// syntaxGo reads durations exactly as Go's
+	// time.ParseDuration does, as used by Kubernetes
+	// metav1.Duration.
+	syntaxGo
+
+	// syntaxSystemd reads durations as systemd time spans, as
+	// used in unit files and timers.
+	syntaxSystemd
+)
+
+// inputSyntaxes maps the names accepted by the -syntax flag to their
+// input syntaxes.
+var inputSyntaxes = map[string]inputSyntax{
+	"haproxy": syntaxHAProxy,
+	"go":      syntaxGo,
+	"systemd": syntaxSystemd,
+}
+
+// String returns the name of the input syntax as accepted by the
+// -syntax flag.
+func (s *inputSyntax) String() string {
+	return flagName(inputSyntaxes, *s)
+}
+
+// Set implements flag.Value, allowing an input syntax to be used
+// directly as a flag.
+func (s *inputSyntax) Set(name string) error {
+	return setFlag(inputSyntaxes, name, s)
+}
+
+// flagName returns the name of value in names, the map behind a flag
+// such as -o, -round or -syntax, or "" if it has none.
+func flagName[T comparable](names map[string]T, value T) string {
+	for name, v := range names {
+		if v == value {
+			return name
+		}
+	}
+	return ""
+}
+
+// setFlag sets *value to the value of name in names, the map behind
+// a flag such as -o, -round or -syntax. An unknown name is rejected
+// with an error listing those accepted.
+func setFlag[T comparable](names map[string]T, name string, value *T) error {
+	v, ok := names[name]
+	if !ok {
+		var accepted []string
+		for name := range names {
+			accepted = append(accepted, name)
+		}
+		sort.Strings(accepted)
+		return fmt.Errorf("must be one of %s", strings.Join(accepted, ", "))
+	}
+	*value = v
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/frobware/comptime"
+)
+
+// systemdUnits maps the units of a systemd time span, as accepted by
+// systemd's parse_sec(), to the duration they stand for. As in
+// systemd, a month is 30.44 days and a year 365.25 days.
+var systemdUnits = map[string]time.Duration{
+	"nsec":    time.Nanosecond,
+	"ns":      time.Nanosecond,
+	"usec":    time.Microsecond,
+	"us":      time.Microsecond,
+	"µs":      time.Microsecond, // U+00B5 MICRO SIGN
+	"μs":      time.Microsecond, // U+03BC GREEK SMALL LETTER MU
+	"msec":    time.Millisecond,
+	"ms":      time.Millisecond,
+	"seconds": time.Second,
+	"second":  time.Second,
+	"sec":     time.Second,
+	"s":       time.Second,
+	"minutes": time.Minute,
+	"minute":  time.Minute,
+	"min":     time.Minute,
+	"m":       time.Minute,
+	"hours":   time.Hour,
+	"hour":    time.Hour,
+	"hr":      time.Hour,
+	"h":       time.Hour,
+	"days":    24 * time.Hour,
+	"day":     24 * time.Hour,
+	"d":       24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"w":       7 * 24 * time.Hour,
+	"months":  2629800 * time.Second,
+	"month":   2629800 * time.Second,
+	"M":       2629800 * time.Second,
+	"years":   31557600 * time.Second,
+	"year":    31557600 * time.Second,
+	"y":       31557600 * time.Second,
+}
+
+// scanSystemdTimespan parses input as a systemd time span, such as
+// "1min 30s", "2weeks" or "5sec", and returns the total along with
+// every problem found, in order of position, as scanDuration does.
+// Values may be separated by whitespace and appear in any order, a
+// number without a unit is in seconds, and a number may have a
+// fraction provided the total is a whole number of microseconds. A
+// number in nanoseconds is kept to the nanosecond instead.
+//
+// The span "infinity" is returned as 0, which HAProxy treats as no
+// timeout.
+func scanSystemdTimespan(input string, d *directive) (time.Duration, []error) {
+	isSpace := func(c byte) bool {
+		return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+	}
+	nextValue := func(position int) int {
+		for position < len(input) && !isSpace(input[position]) && !isDigit(input[position]) {
+			position++
+		}
+		return position
+	}
+
+	if strings.TrimSpace(input) == "infinity" {
+		return 0, nil
+	}
+
+	var errs []error
+	total := durationTotal{max: d.max}
+	values := 0
+
+	for position := 0; position < len(input); {
+		if isSpace(input[position]) {
+			position++
+			continue
+		}
+
+		numStart := position
+		for position < len(input) && isDigit(input[position]) {
+			position++
+		}
+		digits := input[numStart:position]
+		fraction := ""
+		if position < len(input) && input[position] == '.' {
+			position++
+			fracStart := position
+			for position < len(input) && isDigit(input[position]) {
+				position++
+			}
+			fraction = input[fracStart:position]
+		}
+		if digits == "" && fraction == "" {
+			errs = append(errs, syntaxError(comptime.InvalidNumber, numStart))
+			position = nextValue(position + 1)
+			continue
+		}
+		values++
+
+		for position < len(input) && isSpace(input[position]) {
+			position++
+		}
+		unitStart := position
+		for position < len(input) && !isSpace(input[position]) && !isDigit(input[position]) && input[position] != '.' {
+			_, size := utf8.DecodeRuneInString(input[position:])
+			position += size
+		}
+
+		unit := time.Second
+		if position > unitStart {
+			var ok bool
+			if unit, ok = systemdUnits[input[unitStart:position]]; !ok {
+				errs = append(errs, syntaxError(comptime.InvalidUnit, unitStart))
+				continue
+			}
+		}
+
+		number := decimal{digits: digits + fraction, exponent: -len(fraction)}
+		composite, err := number.duration(unit, numStart)
+		if err == nil {
+			err = total.add(composite, numStart)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if values == 0 && len(errs) == 0 {
+		errs = append(errs, syntaxError(comptime.InvalidNumber, len(input)))
+	}
+
+	return total.total, errs
+}